@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	cocoapodsURL = "https://trunk.cocoapods.org/api/v1/pods/%s"
+
+	swiftPackageRe = regexp.MustCompile(`\.package\s*\(\s*(?:name:\s*"[^"]*"\s*,\s*)?url:\s*"([^"]+)"`)
+	podRe          = regexp.MustCompile(`^pod\s+['"]([^'"]+)['"](.*)$`)
+	podSourceRe    = regexp.MustCompile(`^source\s+['"]([^'"]+)['"]`)
+)
+
+func isAppleManifest(p string) bool {
+	switch path.Base(p) {
+	case "Package.swift", "Package.resolved", "Podfile", "Podfile.lock":
+		return true
+	}
+	return false
+}
+
+func parseAppleManifest(name string, body []byte) ([]dependency, language, error) {
+	switch path.Base(name) {
+	case "Package.swift":
+		var deps []dependency
+		for _, m := range swiftPackageRe.FindAllSubmatch(body, -1) {
+			if owner, repo, ok := githubRepo(string(m[1])); ok {
+				deps = append(deps, dependency{Name: owner + "/" + repo})
+			}
+		}
+		return deps, langSwift, nil
+	case "Package.resolved":
+		deps, err := parsePackageResolved(body)
+		return deps, langSwift, err
+	case "Podfile":
+		return parsePodfile(body), langCocoaPods, nil
+	default:
+		return parsePodfileLock(body), langCocoaPods, nil
+	}
+}
+
+// parsePackageResolved reads SwiftPM pins, format v1 (object.pins with
+// repositoryURL) and v2/v3 (pins with location).
+func parsePackageResolved(body []byte) ([]dependency, error) {
+	type pin struct {
+		RepositoryURL string `json:"repositoryURL"`
+		Location      string `json:"location"`
+	}
+	var r struct {
+		Object struct {
+			Pins []pin `json:"pins"`
+		} `json:"object"`
+		Pins []pin `json:"pins"`
+	}
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, err
+	}
+	var deps []dependency
+	for _, p := range append(r.Object.Pins, r.Pins...) {
+		if owner, repo, ok := githubRepo(p.RepositoryURL + p.Location); ok {
+			deps = append(deps, dependency{Name: owner + "/" + repo})
+		}
+	}
+	return deps, nil
+}
+
+// podName strips the subspec: "Firebase/Analytics" is published as Firebase.
+func podName(s string) string {
+	name, _, _ := strings.Cut(strings.TrimSpace(s), "/")
+	return name
+}
+
+func parsePodfile(body []byte) []dependency {
+	var deps []dependency
+	var source string
+	sc := lineScanner(body)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if m := podSourceRe.FindStringSubmatch(line); m != nil {
+			if h := privateRegistryHost(m[1]); h != "" && source == "" {
+				source = h
+			}
+			continue
+		}
+		m := podRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		// pods from git or a local path never reach trunk
+		if strings.Contains(m[2], ":git") || strings.Contains(m[2], ":path") || strings.Contains(m[2], ":podspec") {
+			continue
+		}
+		deps = append(deps, dependency{Name: podName(m[1])})
+	}
+	for i := range deps {
+		deps[i].PrivateRegistry = source
+	}
+	return deps
+}
+
+// parsePodfileLock reads the PODS section, skipping EXTERNAL SOURCES pods and
+// taking the private spec repo of each pod from SPEC REPOS.
+func parsePodfileLock(body []byte) []dependency {
+	var section, repo string
+	versions := make(map[string]string)
+	var order []string
+	repos := make(map[string]string)
+	external := make(map[string]struct{})
+	sc := lineScanner(body)
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, " ") {
+			section = strings.TrimSuffix(strings.TrimSpace(line), ":")
+			continue
+		}
+		trimmed := strings.TrimSpace(line)
+		switch section {
+		case "PODS":
+			if !strings.HasPrefix(line, "  - ") {
+				continue
+			}
+			entry := strings.Trim(strings.TrimSuffix(strings.TrimPrefix(trimmed, "- "), ":"), `"`)
+			name, ver, _ := strings.Cut(entry, " (")
+			name = podName(name)
+			if _, ok := versions[name]; !ok {
+				order = append(order, name)
+			}
+			versions[name] = strings.TrimSuffix(ver, ")")
+		case "SPEC REPOS":
+			if strings.HasPrefix(trimmed, "- ") {
+				repos[podName(strings.Trim(strings.TrimPrefix(trimmed, "- "), `"`))] = repo
+			} else {
+				repo = strings.Trim(strings.TrimSuffix(trimmed, ":"), `"`)
+			}
+		case "EXTERNAL SOURCES":
+			if strings.HasPrefix(line, "  ") && !strings.HasPrefix(line, "   ") {
+				external[podName(strings.Trim(strings.TrimSuffix(trimmed, ":"), `"`))] = struct{}{}
+			}
+		}
+	}
+	var deps []dependency
+	for _, name := range order {
+		if _, ok := external[name]; ok {
+			continue
+		}
+		deps = append(deps, dependency{Name: name, Spec: versions[name], PrivateRegistry: privateRegistryHost(repos[name])})
+	}
+	return deps
+}
+
+// githubRemoteRepojackKind checks a dependency fetched straight from a GitHub
+// repository that is gone (SwiftPM packages, R remotes): if the owner account
+// is gone as well, anyone can recreate it. Missing repositories of existing
+// owners are usually private and skipped.
+func githubRemoteRepojackKind(repo string) (findingKind, string) {
+	owner, _, _ := strings.Cut(repo, "/")
+	status, err := httpHEAD(githubWebURL+"/"+url.PathEscape(owner), map[string]string{"User-Agent": randomUA()})
+	if err != nil || status != http.StatusNotFound {
+		return "", ""
+	}
+	return kindRepojack, "github.com/" + repo
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePodfileLock(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []dependency
+	}{
+		{
+			name: "trunk, private spec repo and external sources",
+			body: `PODS:
+  - AcmeKit (2.0.0)
+  - Alamofire (5.8.1)
+  - Firebase/Analytics (10.18.0):
+    - Firebase/Core
+  - Firebase/Core (10.18.0):
+    - FirebaseAnalytics (~> 10.18.0)
+  - FirebaseAnalytics (10.18.0)
+  - LocalPod (0.1.0)
+  - "SDWebImage/Core (5.18.5)"
+
+DEPENDENCIES:
+  - AcmeKit
+  - Alamofire (~> 5.8)
+  - Firebase/Analytics
+  - LocalPod (from ` + "`../LocalPod`" + `)
+  - SDWebImage
+
+SPEC REPOS:
+  "https://git.acme.corp/ios/specs.git":
+    - AcmeKit
+  trunk:
+    - Alamofire
+    - Firebase
+    - FirebaseAnalytics
+    - SDWebImage
+
+EXTERNAL SOURCES:
+  LocalPod:
+    :path: "../LocalPod"
+
+SPEC CHECKSUMS:
+  AcmeKit: 0123
+  Alamofire: 4567
+
+PODFILE CHECKSUM: 89ab
+
+COCOAPODS: 1.14.3
+`,
+			want: []dependency{
+				{Name: "AcmeKit", Spec: "2.0.0", PrivateRegistry: "git.acme.corp"},
+				{Name: "Alamofire", Spec: "5.8.1"},
+				{Name: "Firebase", Spec: "10.18.0"},
+				{Name: "FirebaseAnalytics", Spec: "10.18.0"},
+				{Name: "SDWebImage", Spec: "5.18.5"},
+			},
+		},
+		{
+			name: "CRLF line endings",
+			body: "PODS:\r\n  - Alamofire (5.8.1)\r\n\r\nCOCOAPODS: 1.14.3\r\n",
+			want: []dependency{{Name: "Alamofire", Spec: "5.8.1"}},
+		},
+		{
+			name: "no pods",
+			body: "PODFILE CHECKSUM: 89ab\n\nCOCOAPODS: 1.14.3\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parsePodfileLock([]byte(tt.body)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePodfile(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []dependency
+	}{
+		{
+			name: "private source",
+			body: `source 'https://cdn.cocoapods.org/'
+source 'https://git.acme.corp/ios/specs.git'
+platform :ios, '14.0'
+
+target 'App' do
+  use_frameworks!
+  pod 'Alamofire', '~> 5.8'
+  pod 'Firebase/Analytics'
+  pod 'AcmeKit', :git => 'https://git.acme.corp/ios/AcmeKit.git'
+  pod 'LocalPod', :path => '../LocalPod'
+  pod 'Remote', :podspec => 'https://example.com/Remote.podspec'
+  pod "AcmeCore"
+  # pod 'Disabled'
+end
+`,
+			want: []dependency{
+				{Name: "Alamofire", PrivateRegistry: "git.acme.corp"},
+				{Name: "Firebase", PrivateRegistry: "git.acme.corp"},
+				{Name: "AcmeCore", PrivateRegistry: "git.acme.corp"},
+			},
+		},
+		{
+			name: "trunk only",
+			body: "platform :ios, '14.0'\ntarget 'App' do\n  pod 'Alamofire'\nend\n",
+			want: []dependency{{Name: "Alamofire"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parsePodfile([]byte(tt.body)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSwiftManifests(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		body string
+		want []dependency
+	}{
+		{
+			name: "Package.swift",
+			file: "Package.swift",
+			body: `// swift-tools-version:5.9
+import PackageDescription
+
+let package = Package(
+    name: "App",
+    dependencies: [
+        .package(url: "https://github.com/apple/swift-argument-parser.git", from: "1.2.0"),
+        .package(name: "AcmeKit", url: "https://github.com/acme-ios/AcmeKit", .branch("main")),
+        .package(url: "https://git.acme.corp/ios/Internal.git", from: "1.0.0"),
+        .package(path: "../Local"),
+    ]
+)
+`,
+			want: []dependency{{Name: "apple/swift-argument-parser"}, {Name: "acme-ios/AcmeKit"}},
+		},
+		{
+			name: "Package.resolved v1",
+			file: "Package.resolved",
+			body: `{
+  "object": {
+    "pins": [
+      {"package": "swift-log", "repositoryURL": "https://github.com/apple/swift-log.git", "state": {"version": "1.5.3"}}
+    ]
+  },
+  "version": 1
+}`,
+			want: []dependency{{Name: "apple/swift-log"}},
+		},
+		{
+			name: "Package.resolved v2",
+			file: "Package.resolved",
+			body: `{
+  "pins": [
+    {"identity": "acmekit", "kind": "remoteSourceControl", "location": "https://github.com/acme-ios/AcmeKit", "state": {"revision": "abc"}},
+    {"identity": "internal", "kind": "remoteSourceControl", "location": "https://git.acme.corp/ios/Internal.git", "state": {"version": "1.0.0"}}
+  ],
+  "version": 2
+}`,
+			want: []dependency{{Name: "acme-ios/AcmeKit"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, lang, err := parseAppleManifest(tt.file, []byte(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if lang != langSwift {
+				t.Errorf("language %q, want %q", lang, langSwift)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
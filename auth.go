@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// authProfile holds the credentials used for target hosts matching one of
+// its patterns. Profiles are never applied to registry hosts.
+type authProfile struct {
+	Hosts      []string          `json:"hosts"`
+	Bearer     string            `json:"bearer"`
+	Basic      string            `json:"basic"`
+	Cookie     string            `json:"cookie"`
+	CookieFile string            `json:"cookie_file"`
+	Headers    map[string]string `json:"headers"`
+	Cert       string            `json:"cert"`
+	Key        string            `json:"key"`
+	CA         string            `json:"ca"`
+
+	jar    http.CookieJar
+	client *http.Client
+}
+
+var authProfiles []*authProfile
+
+func loadAuthProfiles(name string) error {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	var profiles []*authProfile
+	if err := json.Unmarshal(b, &profiles); err != nil {
+		return err
+	}
+	for i, p := range profiles {
+		if len(p.Hosts) == 0 {
+			return fmt.Errorf("profile %d: no hosts", i)
+		}
+		if p.CookieFile != "" {
+			if p.jar, err = readCookieFile(p.CookieFile); err != nil {
+				return fmt.Errorf("profile %d: %v", i, err)
+			}
+		}
+		if p.Cert != "" || p.CA != "" {
+			if p.client, err = tlsClient(p); err != nil {
+				return fmt.Errorf("profile %d: %v", i, err)
+			}
+		}
+	}
+	authProfiles = profiles
+	return nil
+}
+
+// tlsClient returns a client presenting the profile's client certificate
+// (and trusting its CA); everything else is shared with httpClient.
+func tlsClient(p *authProfile) (*http.Client, error) {
+	cfg := httpClient.Transport.(*http.Transport).TLSClientConfig.Clone()
+	if p.Cert != "" {
+		key := p.Key
+		if key == "" {
+			key = p.Cert
+		}
+		cert, err := tls.LoadX509KeyPair(p.Cert, key)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	if p.CA != "" {
+		pem, err := os.ReadFile(p.CA)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", p.CA)
+		}
+		cfg.RootCAs = pool
+		cfg.InsecureSkipVerify = false
+	}
+	tr := httpClient.Transport.(*http.Transport).Clone()
+	tr.TLSClientConfig = cfg
+	return &http.Client{Timeout: httpClient.Timeout, Transport: tr}, nil
+}
+
+// readCookieFile loads a Netscape cookies.txt export (curl, browser add-ons).
+func readCookieFile(name string) (http.CookieJar, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	jar, _ := cookiejar.New(nil)
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := sc.Text()
+		httpOnly := strings.HasPrefix(line, "#HttpOnly_")
+		line = strings.TrimPrefix(line, "#HttpOnly_")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 7 {
+			continue
+		}
+		domain, sub, cpath, secure, expires, name, value := fields[0], fields[1], fields[2], fields[3], fields[4], fields[5], fields[6]
+		c := &http.Cookie{Name: name, Value: value, Path: cpath, Secure: strings.EqualFold(secure, "TRUE"), HttpOnly: httpOnly}
+		if strings.EqualFold(sub, "TRUE") {
+			c.Domain = domain
+		}
+		if sec, err := strconv.ParseInt(expires, 10, 64); err == nil && sec > 0 {
+			c.Expires = time.Unix(sec, 0)
+		}
+		scheme := "http"
+		if c.Secure {
+			scheme = "https"
+		}
+		u := &url.URL{Scheme: scheme, Host: strings.TrimPrefix(domain, "."), Path: cpath}
+		jar.SetCookies(u, []*http.Cookie{c})
+	}
+	return jar, sc.Err()
+}
+
+func authFor(u *url.URL) *authProfile {
+	host := strings.ToLower(u.Hostname())
+	if isRegistryHost(host) {
+		return nil
+	}
+	for _, p := range authProfiles {
+		for _, pat := range p.Hosts {
+			if ok, _ := path.Match(strings.ToLower(pat), host); ok {
+				return p
+			}
+			if ok, _ := path.Match(strings.ToLower(pat), strings.ToLower(u.Host)); ok {
+				return p
+			}
+		}
+	}
+	return nil
+}
+
+// apply adds the profile's credentials to req without overriding headers
+// the request already carries (such as ones captured from a proxy).
+func (p *authProfile) apply(req *http.Request) {
+	set := func(k, v string) {
+		if req.Header.Get(k) == "" {
+			req.Header.Set(k, v)
+		}
+	}
+	for k, v := range p.Headers {
+		set(k, v)
+	}
+	switch {
+	case p.Bearer != "":
+		set("Authorization", "Bearer "+p.Bearer)
+	case p.Basic != "":
+		set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(p.Basic)))
+	}
+	var cookies []string
+	if p.Cookie != "" {
+		cookies = append(cookies, p.Cookie)
+	}
+	if p.jar != nil {
+		for _, c := range p.jar.Cookies(req.URL) {
+			cookies = append(cookies, c.Name+"="+c.Value)
+		}
+	}
+	if len(cookies) > 0 {
+		set("Cookie", strings.Join(cookies, "; "))
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+var bowerRegistryURL = "https://registry.bower.io/packages/%s"
+
+func isBowerManifest(p string) bool {
+	switch strings.ToLower(path.Base(p)) {
+	case "bower.json", "component.json":
+		return true
+	}
+	return false
+}
+
+// parseBowerManifest reads bower.json and component.json dependencies.
+// Versions that are URLs, git endpoints or paths are not registry names, and
+// neither are component's "owner/repo" GitHub sources.
+func parseBowerManifest(body []byte) ([]dependency, error) {
+	var m struct {
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+		Development     map[string]string `json:"development"`
+	}
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, err
+	}
+	var deps []dependency
+	for _, set := range []struct {
+		deps map[string]string
+		typ  depType
+	}{{m.Dependencies, ""}, {m.DevDependencies, depDev}, {m.Development, depDev}} {
+		for name, spec := range set.deps {
+			if strings.Contains(name, "/") && !strings.HasPrefix(name, "@") {
+				continue
+			}
+			s := strings.ToLower(spec)
+			if strings.Contains(s, "://") || strings.HasPrefix(s, "git") || strings.Contains(s, "/") {
+				continue
+			}
+			// bower ranges match git tags, not npm releases
+			deps = append(deps, dependency{Name: name, Type: set.typ})
+		}
+	}
+	return deps, nil
+}
+
+// bowerRegistered reports whether name is registered on the Bower registry,
+// which is where bower itself resolves it.
+func bowerRegistered(name string) bool {
+	r := packageLookups.do("bower:"+name, func() (packageStatus, int) {
+		_, status, err := httpGET(strings.Replace(bowerRegistryURL, "%s", url.PathEscape(name), 1), map[string]string{"User-Agent": randomUA()})
+		if err != nil {
+			return packageStatus{}, 0
+		}
+		return packageStatus{unclaimed: status == http.StatusNotFound, status: status}, 0
+	})
+	if !shareable(r) {
+		packageLookups.forget("bower:" + name)
+	}
+	return r.status == http.StatusOK
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// bucketListing is an anonymously listable S3 or GCS bucket: base is the URL
+// objects hang off, prefix narrows the listing.
+type bucketListing struct {
+	base   string
+	prefix string
+}
+
+func parseBucketURL(raw string) (bucketListing, bool) {
+	p, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || p.Host == "" {
+		return bucketListing{}, false
+	}
+	prefix := strings.TrimPrefix(p.Path, "/")
+	if q := p.Query().Get("prefix"); q != "" {
+		prefix = q
+	}
+	switch p.Scheme {
+	case "s3":
+		return bucketListing{base: "https://" + p.Host + ".s3.amazonaws.com", prefix: prefix}, true
+	case "gs":
+		return bucketListing{base: "https://storage.googleapis.com/" + p.Host, prefix: prefix}, true
+	case "http", "https":
+	default:
+		return bucketListing{}, false
+	}
+
+	// only directory-like URLs are listings, object URLs are scanned as is
+	if p.Path != "" && !strings.HasSuffix(p.Path, "/") {
+		return bucketListing{}, false
+	}
+	host := strings.ToLower(p.Hostname())
+	origin := p.Scheme + "://" + p.Host
+	pathStyle := host == "storage.googleapis.com" || host == "s3.amazonaws.com" ||
+		(strings.HasPrefix(host, "s3.") || strings.HasPrefix(host, "s3-")) && strings.HasSuffix(host, ".amazonaws.com")
+	switch {
+	case pathStyle:
+		bucket, rest, _ := strings.Cut(strings.TrimPrefix(p.Path, "/"), "/")
+		if bucket == "" {
+			return bucketListing{}, false
+		}
+		if p.Query().Get("prefix") == "" {
+			prefix = rest
+		}
+		return bucketListing{base: origin + "/" + bucket, prefix: prefix}, true
+	case strings.Contains(host, ".s3.") && strings.HasSuffix(host, ".amazonaws.com"),
+		strings.Contains(host, ".s3-") && strings.HasSuffix(host, ".amazonaws.com"),
+		strings.HasSuffix(host, ".storage.googleapis.com"):
+		return bucketListing{base: origin, prefix: prefix}, true
+	}
+	return bucketListing{}, false
+}
+
+func (b bucketListing) objectURL(key string) string {
+	parts := strings.Split(key, "/")
+	for i, s := range parts {
+		parts[i] = url.PathEscape(s)
+	}
+	return b.base + "/" + strings.Join(parts, "/")
+}
+
+// list pages through a ListObjects (v1) listing, which S3, GCS and most
+// S3-compatible stores answer for public buckets.
+func (b bucketListing) list() ([]string, error) {
+	var urls []string
+	marker := ""
+	for {
+		q := url.Values{}
+		if b.prefix != "" {
+			q.Set("prefix", b.prefix)
+		}
+		if marker != "" {
+			q.Set("marker", marker)
+		}
+		body, status, err := httpGET(b.base+"/?"+q.Encode(), targetHeaders())
+		if err != nil {
+			return urls, err
+		}
+		if status != http.StatusOK {
+			return urls, fmt.Errorf("bucket listing %s returned %d", b.base, status)
+		}
+		var res struct {
+			IsTruncated bool   `xml:"IsTruncated"`
+			NextMarker  string `xml:"NextMarker"`
+			Contents    []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+		}
+		if err := xml.Unmarshal(body, &res); err != nil {
+			return urls, err
+		}
+		for _, c := range res.Contents {
+			urls = append(urls, b.objectURL(c.Key))
+		}
+		if !res.IsTruncated || len(res.Contents) == 0 {
+			return urls, nil
+		}
+		marker = res.NextMarker
+		if marker == "" {
+			marker = res.Contents[len(res.Contents)-1].Key
+		}
+	}
+}
+
+// expandBuckets replaces bucket listing inputs with the manifest and bundle
+// objects they contain.
+func expandBuckets(inputs []string) []string {
+	out := make([]string, 0, len(inputs))
+	for _, in := range inputs {
+		b, ok := parseBucketURL(in)
+		if !ok {
+			out = append(out, in)
+			continue
+		}
+		objs, err := b.list()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "bucket %s: %v\n", in, err)
+		}
+		out = append(out, filterManifestURLs(objs)...)
+	}
+	return out
+}
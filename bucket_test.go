@@ -0,0 +1,111 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParseBucketURL(t *testing.T) {
+	tests := []struct {
+		raw    string
+		want   bucketListing
+		wantOK bool
+	}{
+		// URIs
+		{"s3://acme-builds", bucketListing{base: "https://acme-builds.s3.amazonaws.com"}, true},
+		{"s3://acme-builds/web/", bucketListing{base: "https://acme-builds.s3.amazonaws.com", prefix: "web/"}, true},
+		{"gs://acme-artifacts/release", bucketListing{base: "https://storage.googleapis.com/acme-artifacts", prefix: "release"}, true},
+		{"s3:///nobucket", bucketListing{}, false},
+		// virtual-hosted S3 and GCS
+		{"https://acme-builds.s3.amazonaws.com/", bucketListing{base: "https://acme-builds.s3.amazonaws.com"}, true},
+		{"https://acme-builds.s3.eu-west-1.amazonaws.com/web/", bucketListing{base: "https://acme-builds.s3.eu-west-1.amazonaws.com", prefix: "web/"}, true},
+		{"https://acme-builds.s3-us-west-2.amazonaws.com", bucketListing{base: "https://acme-builds.s3-us-west-2.amazonaws.com"}, true},
+		{"https://acme-artifacts.storage.googleapis.com/?prefix=ci/", bucketListing{base: "https://acme-artifacts.storage.googleapis.com", prefix: "ci/"}, true},
+		// path-style
+		{"https://s3.amazonaws.com/acme-builds/", bucketListing{base: "https://s3.amazonaws.com/acme-builds"}, true},
+		{"https://s3.eu-central-1.amazonaws.com/acme-builds/web/", bucketListing{base: "https://s3.eu-central-1.amazonaws.com/acme-builds", prefix: "web/"}, true},
+		{"https://storage.googleapis.com/acme-artifacts/", bucketListing{base: "https://storage.googleapis.com/acme-artifacts"}, true},
+		{"https://storage.googleapis.com/acme-artifacts/?prefix=ci/", bucketListing{base: "https://storage.googleapis.com/acme-artifacts", prefix: "ci/"}, true},
+		{"https://storage.googleapis.com/", bucketListing{}, false},
+		// objects and other sites are scanned as is
+		{"https://acme-builds.s3.amazonaws.com/web/package-lock.json", bucketListing{}, false},
+		{"https://storage.googleapis.com/acme-artifacts/package.json", bucketListing{}, false},
+		{"https://example.com/", bucketListing{}, false},
+		{"https://s3.example.com/bucket/", bucketListing{}, false},
+		{"ftp://acme-builds.s3.amazonaws.com/", bucketListing{}, false},
+		{"not a url", bucketListing{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseBucketURL(tt.raw)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("parseBucketURL(%q) = %+v, %v, want %+v, %v", tt.raw, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestBucketObjectURL(t *testing.T) {
+	b := bucketListing{base: "https://acme-builds.s3.amazonaws.com"}
+	tests := []struct{ key, want string }{
+		{"package-lock.json", "https://acme-builds.s3.amazonaws.com/package-lock.json"},
+		{"web/dist/app.js", "https://acme-builds.s3.amazonaws.com/web/dist/app.js"},
+		{"release 1.0/package.json", "https://acme-builds.s3.amazonaws.com/release%201.0/package.json"},
+		{"a?b#c/yarn.lock", "https://acme-builds.s3.amazonaws.com/a%3Fb%23c/yarn.lock"},
+	}
+	for _, tt := range tests {
+		if got := b.objectURL(tt.key); got != tt.want {
+			t.Errorf("objectURL(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestBucketList(t *testing.T) {
+	pages := map[string]string{
+		"": `<?xml version="1.0" encoding="UTF-8"?>
+<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+  <Name>acme-builds</Name>
+  <Prefix>web/</Prefix>
+  <IsTruncated>true</IsTruncated>
+  <Contents><Key>web/package-lock.json</Key><Size>1024</Size></Contents>
+  <Contents><Key>web/README.md</Key><Size>10</Size></Contents>
+</ListBucketResult>`,
+		// GCS omits NextMarker, so the last key continues the listing
+		"web/README.md": `<ListBucketResult>
+  <IsTruncated>false</IsTruncated>
+  <Contents><Key>web/dist/main.js</Key></Contents>
+</ListBucketResult>`,
+	}
+	var prefixes []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefixes = append(prefixes, r.URL.Query().Get("prefix"))
+		page, ok := pages[r.URL.Query().Get("marker")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(page))
+	}))
+	defer srv.Close()
+
+	got, err := bucketListing{base: srv.URL, prefix: "web/"}.list()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{srv.URL + "/web/package-lock.json", srv.URL + "/web/README.md", srv.URL + "/web/dist/main.js"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("list = %q\nwant %q", got, want)
+	}
+	if !reflect.DeepEqual(prefixes, []string{"web/", "web/"}) {
+		t.Errorf("prefixes sent = %q", prefixes)
+	}
+
+	denied := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`<Error><Code>AccessDenied</Code></Error>`))
+	}))
+	defer denied.Close()
+	if _, err := (bucketListing{base: denied.URL}).list(); err == nil {
+		t.Error("a 403 listing did not fail")
+	}
+}
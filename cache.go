@@ -0,0 +1,177 @@
+package main
+
+import (
+	"container/list"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// lruCache is a size-bounded cache: once it holds more than opts.cacheEntries
+// entries or opts.cacheMem estimated bytes, the least recently used entries
+// are dropped.
+type lruCache[V any] struct {
+	name string
+
+	mu    sync.Mutex
+	ll    *list.List
+	m     map[string]*list.Element
+	bytes int64
+
+	hits, misses, evictions atomic.Int64
+}
+
+type lruEntry[V any] struct {
+	key  string
+	val  V
+	size int64
+}
+
+// entryOverhead approximates the map, list and entry bookkeeping per key.
+const entryOverhead = 128
+
+func newLRU[V any](name string) *lruCache[V] {
+	return &lruCache[V]{name: name, ll: list.New(), m: make(map[string]*list.Element)}
+}
+
+// peek looks key up without counting a hit or miss.
+func (c *lruCache[V]) peek(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.m[key]; ok {
+		c.ll.MoveToFront(el)
+		return el.Value.(*lruEntry[V]).val, true
+	}
+	var zero V
+	return zero, false
+}
+
+func (c *lruCache[V]) record(hit bool) {
+	if hit {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+}
+
+func (c *lruCache[V]) get(key string) (V, bool) {
+	v, ok := c.peek(key)
+	c.record(ok)
+	return v, ok
+}
+
+// add stores val under key; size is the estimated memory held by val.
+func (c *lruCache[V]) add(key string, val V, size int) {
+	e := &lruEntry[V]{key: key, val: val, size: int64(len(key)+size) + entryOverhead}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.m[key]; ok {
+		c.bytes -= el.Value.(*lruEntry[V]).size
+		el.Value = e
+		c.ll.MoveToFront(el)
+	} else {
+		c.m[key] = c.ll.PushFront(e)
+	}
+	c.bytes += e.size
+	for c.ll.Len() > 1 && c.full() {
+		old := c.ll.Back()
+		c.ll.Remove(old)
+		delete(c.m, old.Value.(*lruEntry[V]).key)
+		c.bytes -= old.Value.(*lruEntry[V]).size
+		c.evictions.Add(1)
+	}
+}
+
+func (c *lruCache[V]) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.m[key]; ok {
+		c.ll.Remove(el)
+		delete(c.m, key)
+		c.bytes -= el.Value.(*lruEntry[V]).size
+	}
+}
+
+func (c *lruCache[V]) full() bool {
+	return (opts.cacheEntries > 0 && c.ll.Len() > opts.cacheEntries) ||
+		(opts.cacheMem > 0 && c.bytes > int64(opts.cacheMem))
+}
+
+func (c *lruCache[V]) reset() {
+	c.mu.Lock()
+	c.ll.Init()
+	c.m = make(map[string]*list.Element)
+	c.bytes = 0
+	c.mu.Unlock()
+}
+
+func (c *lruCache[V]) stats() string {
+	c.mu.Lock()
+	n, b := c.ll.Len(), c.bytes
+	c.mu.Unlock()
+	return fmt.Sprintf("%s cache: %d hits, %d misses, %d evictions, %d entries (~%s)",
+		c.name, c.hits.Load(), c.misses.Load(), c.evictions.Load(), n, formatSize(b))
+}
+
+func (c *lruCache[V]) counters() (name string, hits, misses, evictions int64, entries int) {
+	c.mu.Lock()
+	entries = c.ll.Len()
+	c.mu.Unlock()
+	return c.name, c.hits.Load(), c.misses.Load(), c.evictions.Load(), entries
+}
+
+type statsCache interface {
+	stats() string
+	counters() (name string, hits, misses, evictions int64, entries int)
+}
+
+func allCaches() []statsCache {
+	return []statsCache{headCache, packageLookups.cache, npmMetaLookups.cache, pypiMetaLookups.cache, dnsLookups.cache,
+		githubLookups.cache, deadURLLookups.cache, extensionLookups.cache, npmScopeLookups.cache}
+}
+
+func printCacheStats() {
+	for _, c := range allCaches() {
+		fmt.Fprintln(os.Stderr, c.stats())
+	}
+}
+
+// sizeFlag is a byte count that accepts KB, MB and GB suffixes.
+type sizeFlag int64
+
+func (s *sizeFlag) String() string { return formatSize(int64(*s)) }
+
+func (s *sizeFlag) Set(v string) error {
+	v = strings.ToUpper(strings.TrimSpace(v))
+	mult := int64(1)
+	for _, u := range []struct {
+		suffix string
+		mult   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(v, u.suffix) {
+			v, mult = strings.TrimSpace(strings.TrimSuffix(v, u.suffix)), u.mult
+			break
+		}
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid size %q", v)
+	}
+	*s = sizeFlag(n * mult)
+	return nil
+}
+
+func formatSize(b int64) string {
+	switch {
+	case b >= 1<<30:
+		return fmt.Sprintf("%.1fGB", float64(b)/(1<<30))
+	case b >= 1<<20:
+		return fmt.Sprintf("%.1fMB", float64(b)/(1<<20))
+	case b >= 1<<10:
+		return fmt.Sprintf("%.1fKB", float64(b)/(1<<10))
+	}
+	return fmt.Sprintf("%dB", b)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// confirmation is a callback from the install hook of a published PoC
+// package: proof the target's builds resolve the name from the public
+// registry.
+type confirmation struct {
+	pocRecord
+	Protocol      string    `json:"protocol"`
+	RemoteAddress string    `json:"remote_address,omitempty"`
+	At            time.Time `json:"at"`
+	Detail        string    `json:"detail,omitempty"`
+}
+
+func loadPoCSession(name string) (*pocSession, error) {
+	if st, err := os.Stat(name); err == nil && st.IsDir() {
+		name = filepath.Join(name, "callbacks.json")
+	}
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var s pocSession
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func matchInteractions(s *pocSession, its []interaction) []confirmation {
+	var out []confirmation
+	for _, it := range its {
+		labels := strings.Split(strings.ToLower(it.FullID), ".")
+		for _, p := range s.Packages {
+			for _, l := range labels {
+				if l == p.Token {
+					out = append(out, confirmation{pocRecord: p, Protocol: it.Protocol, RemoteAddress: it.RemoteAddress, At: it.Timestamp})
+					break
+				}
+			}
+		}
+	}
+	return out
+}
+
+// matchLog finds the PoC hosts in the query log of a self-hosted DNS or OOB
+// server; any line mentioning a host counts.
+func matchLog(s *pocSession, name string) ([]confirmation, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	st, _ := f.Stat()
+	var out []confirmation
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := strings.ToLower(sc.Text())
+		for _, p := range s.Packages {
+			if strings.Contains(line, strings.ToLower(p.Host)) {
+				out = append(out, confirmation{pocRecord: p, Protocol: "log", At: st.ModTime(), Detail: strings.TrimSpace(sc.Text())})
+			}
+		}
+	}
+	return out, sc.Err()
+}
+
+func printConfirmation(c confirmation) {
+	fields := []string{c.Package, registryFor(c.Language), "confirmed", c.Protocol}
+	fmt.Printf("%s[%s]%s", red, strings.Join(fields, "|"), reset)
+	if c.RemoteAddress != "" {
+		fmt.Printf(" from %s", c.RemoteAddress)
+	}
+	if c.Detail != "" {
+		fmt.Printf(" %s", c.Detail)
+	} else {
+		fmt.Printf(" at %s", c.At.UTC().Format(time.RFC3339))
+	}
+	fmt.Println()
+}
+
+func runCallbacks(args []string) error {
+	fs := flag.NewFlagSet("callbacks", flag.ExitOnError)
+	wait := fs.Duration("wait", 0, "keep polling for this long (0 = poll once)")
+	interval := fs.Duration("interval", 10*time.Second, "time between polls")
+	logFile := fs.String("log", "", "query log of your own DNS/OOB server to search instead of interactsh")
+	db := fs.String("db", "", "SQLite database to record confirmations in")
+	fs.BoolVar(&opts.noColor, "no-color", false, "disable ANSI colors")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: dchero callbacks [flags] <poc dir | callbacks.json>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if opts.noColor {
+		red, reset = "", ""
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("the poc directory or its callbacks.json is required")
+	}
+	s, err := loadPoCSession(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if s.Interactsh == nil && *logFile == "" {
+		return errors.New("the PoC packages were not generated with -interactsh, pass the callback server's query log with -log")
+	}
+	if *db != "" {
+		if err := checkSQLite(); err != nil {
+			return err
+		}
+	}
+
+	record := func(cc []confirmation) {
+		for _, c := range cc {
+			printConfirmation(c)
+		}
+		if *db != "" && len(cc) > 0 {
+			if err := recordConfirmations(*db, cc); err != nil {
+				fmt.Fprintf(os.Stderr, "db error: %v\n", err)
+			}
+		}
+	}
+
+	if *logFile != "" {
+		cc, err := matchLog(s, *logFile)
+		if err != nil {
+			return err
+		}
+		record(cc)
+		return nil
+	}
+
+	deadline := time.Now().Add(*wait)
+	for {
+		its, err := s.Interactsh.poll()
+		if err != nil {
+			return err
+		}
+		record(matchInteractions(s, its))
+		if time.Now().Add(*interval).After(deadline) {
+			return nil
+		}
+		time.Sleep(*interval)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"net/textproto"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Headers captured by a proxy are replayed when the same URL (or, failing
+// that, another URL on the same host) is fetched, so manifests behind a login
+// can be re-downloaded. They are never sent to any other host.
+var (
+	capturedURLHeaders  = make(map[string]map[string]string)
+	capturedHostHeaders = make(map[string]map[string]string)
+	capturedMu          sync.Mutex
+)
+
+var skipCapturedHeaders = map[string]struct{}{
+	"Host":                {},
+	"Content-Length":      {},
+	"Content-Type":        {},
+	"Connection":          {},
+	"Keep-Alive":          {},
+	"Accept-Encoding":     {},
+	"Transfer-Encoding":   {},
+	"Te":                  {},
+	"Upgrade":             {},
+	"If-None-Match":       {},
+	"If-Modified-Since":   {},
+	"Range":               {},
+	"If-Range":            {},
+	"Proxy-Connection":    {},
+	"Proxy-Authorization": {},
+}
+
+func recordCaptured(u string, headers map[string]string) {
+	p, err := url.Parse(u)
+	if err != nil || p.Host == "" {
+		return
+	}
+	kept := make(map[string]string)
+	for k, v := range headers {
+		if strings.HasPrefix(k, ":") {
+			continue
+		}
+		k = textproto.CanonicalMIMEHeaderKey(k)
+		if _, skip := skipCapturedHeaders[k]; skip {
+			continue
+		}
+		kept[k] = v
+	}
+	if len(kept) == 0 {
+		return
+	}
+	capturedMu.Lock()
+	defer capturedMu.Unlock()
+	capturedURLHeaders[u] = kept
+	if _, ok := kept["Cookie"]; ok {
+		capturedHostHeaders[strings.ToLower(p.Host)] = kept
+	} else if _, ok := kept["Authorization"]; ok {
+		capturedHostHeaders[strings.ToLower(p.Host)] = kept
+	}
+}
+
+func capturedHeaders(u string) map[string]string {
+	capturedMu.Lock()
+	defer capturedMu.Unlock()
+	if h, ok := capturedURLHeaders[u]; ok {
+		return h
+	}
+	if p, err := url.Parse(u); err == nil {
+		return capturedHostHeaders[strings.ToLower(p.Host)]
+	}
+	return nil
+}
+
+func readHAR(name string) ([]string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var har struct {
+		Log struct {
+			Entries []struct {
+				Request struct {
+					Method  string `json:"method"`
+					URL     string `json:"url"`
+					Headers []struct {
+						Name  string `json:"name"`
+						Value string `json:"value"`
+					} `json:"headers"`
+				} `json:"request"`
+			} `json:"entries"`
+		} `json:"log"`
+	}
+	if err := json.NewDecoder(f).Decode(&har); err != nil {
+		return nil, err
+	}
+
+	var urls []string
+	for _, e := range har.Log.Entries {
+		if e.Request.Method != "" && e.Request.Method != "GET" {
+			continue
+		}
+		h := make(map[string]string)
+		for _, hd := range e.Request.Headers {
+			h[hd.Name] = hd.Value
+		}
+		recordCaptured(e.Request.URL, h)
+		urls = append(urls, e.Request.URL)
+	}
+	return urls, nil
+}
+
+// readBurp reads a Burp Suite "Save items" XML export.
+func readBurp(name string) ([]string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var items struct {
+		Items []struct {
+			URL     string `xml:"url"`
+			Method  string `xml:"method"`
+			Request struct {
+				Base64 bool   `xml:"base64,attr"`
+				Data   string `xml:",chardata"`
+			} `xml:"request"`
+		} `xml:"item"`
+	}
+	if err := xml.NewDecoder(f).Decode(&items); err != nil {
+		return nil, err
+	}
+
+	var urls []string
+	for _, it := range items.Items {
+		if it.Method != "" && it.Method != "GET" {
+			continue
+		}
+		raw := it.Request.Data
+		if it.Request.Base64 {
+			b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(raw))
+			if err != nil {
+				continue
+			}
+			raw = string(b)
+		}
+		recordCaptured(it.URL, parseRawRequestHeaders(raw))
+		urls = append(urls, it.URL)
+	}
+	return urls, nil
+}
+
+func parseRawRequestHeaders(raw string) map[string]string {
+	h := make(map[string]string)
+	sc := bufio.NewScanner(strings.NewReader(raw))
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	first := true
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if first {
+			first = false
+			continue
+		}
+		if line == "" {
+			break
+		}
+		k, v, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		h[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return h
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+type finding struct {
+	URL string `json:"url"`
+	vuln
+}
+
+func (f finding) key() string {
+	return string(f.Language) + "|" + f.Package + "|" + string(f.Kind) + "|" + f.URL
+}
+
+type delta struct {
+	New      []finding `json:"new"`
+	Resolved []finding `json:"resolved"`
+}
+
+type daemonState struct {
+	known map[string]finding
+}
+
+func (s *daemonState) update(results []scanResult) delta {
+	var d delta
+	current := make(map[string]finding)
+	failed := make(map[string]struct{})
+	for _, r := range results {
+		if r.err != nil {
+			// a manifest that is gone resolves its findings, a failed fetch does not
+			if transient(r.err) {
+				failed[r.u] = struct{}{}
+			}
+			continue
+		}
+		for _, v := range r.vulns {
+			f := finding{URL: r.u, vuln: v}
+			current[f.key()] = f
+			if _, ok := s.known[f.key()]; !ok {
+				d.New = append(d.New, f)
+			}
+		}
+	}
+	for k, f := range s.known {
+		if _, ok := current[k]; ok {
+			continue
+		}
+		// a URL that failed this round keeps its previous findings
+		if _, ok := failed[f.URL]; ok {
+			current[k] = f
+			continue
+		}
+		d.Resolved = append(d.Resolved, f)
+	}
+	s.known = current
+	return d
+}
+
+func findingResults(ff []finding) []scanResult {
+	out := make([]scanResult, 0, len(ff))
+	for _, f := range ff {
+		out = append(out, scanResult{u: f.URL, vulns: []vuln{f.vuln}})
+	}
+	return out
+}
+
+func resetCaches() {
+	headCache.reset()
+	packageLookups.reset()
+	npmMetaLookups.reset()
+	pypiMetaLookups.reset()
+	dnsLookups.reset()
+	deadURLLookups.reset()
+	extensionLookups.reset()
+	npmScopeLookups.reset()
+}
+
+func notifyWebhook(webhook string, d delta) error {
+	return postJSON(webhook, nil, d)
+}
+
+func runDaemon(loadTargets func() []string) {
+	interval := opts.interval
+	if interval <= 0 {
+		interval = 6 * time.Hour
+	}
+	state := &daemonState{known: make(map[string]finding)}
+	if opts.metrics != "" {
+		startMetricsServer(opts.metrics)
+	}
+	for {
+		start := time.Now()
+		resetCaches()
+		targets := loadTargets()
+		run := startRun(len(targets))
+		results := scanAll(targets)
+		saveResults(results, run)
+		recordRound(results, time.Since(start).Seconds(), time.Now().Unix())
+		d := state.update(results)
+
+		printResults(findingResults(d.New))
+		for _, f := range d.Resolved {
+			fmt.Fprintf(os.Stderr, "resolved: [%s|%d|%s|%s] %s\n", f.Package, f.Status, f.Language, f.Kind, f.URL)
+		}
+		if opts.webhook != "" && (len(d.New) > 0 || len(d.Resolved) > 0) {
+			if err := notifyWebhook(opts.webhook, d); err != nil {
+				fmt.Fprintf(os.Stderr, "webhook error: %v\n", err)
+			}
+		}
+
+		if wait := interval - time.Since(start); wait > 0 {
+			time.Sleep(wait)
+		}
+	}
+}
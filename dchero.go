@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"compress/gzip"
 	"crypto/tls"
 	"encoding/json"
 	"flag"
@@ -13,19 +14,28 @@ import (
 	"os"
 	"path"
 	"regexp"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 )
 
 const (
-	red   = "\x1b[31m"
-	reset = "\x1b[0m"
+	ansiRed   = "\x1b[31m"
+	ansiReset = "\x1b[0m"
 )
 
+// red and reset are emptied when color is off, so output piped into other
+// tools never carries escape sequences.
 var (
-	manifestRe = regexp.MustCompile(`(?i)(?:^|/)(package\.json|package-lock\.json|yarn\.lock|pnpm-lock\.yaml|requirements\.txt|pyproject\.toml|Pipfile|Pipfile\.lock|constraints\.txt|setup\.py|composer\.json|go\.mod)(?:$|[?#/])`)
+	red   = ansiRed
+	reset = ansiReset
+)
+
+var (
+	manifestRe = regexp.MustCompile(`(?i)(?:^|/)(package\.json|package-lock\.json|npm-shrinkwrap\.json|bower\.json|component\.json|yarn\.lock|pnpm-lock\.yaml|requirements\.txt|pyproject\.toml|Pipfile|Pipfile\.lock|constraints\.txt|setup\.py|setup\.cfg|Package\.swift|Package\.resolved|Podfile|Podfile\.lock|Chart\.yaml|requirements\.yaml|manifest\.json|DESCRIPTION|renv\.lock|composer\.json|go\.mod)(?:$|[?#/])`)
 	reqSplitRe = regexp.MustCompile(`[<>=!~\[\];\s]`)
 
 	importReqRe = regexp.MustCompile(`(?:require\(\s*['"]([^'"]+)['"]\s*\))|(?:import\s+(?:.+?\s+from\s+)?['"]([^'"]+)['"])`)
@@ -39,11 +49,11 @@ var (
 		},
 	}
 
-	npmURL  = "https://registry.npmjs.org/%s/"
-	pypiURL = "https://pypi.org/project/%s/"
+	npmURL      = "https://registry.npmjs.org/%s/"
+	pypiURL     = "https://pypi.org/project/%s/"
+	pypiJSONURL = "https://pypi.org/pypi/%s/json"
 
-	headCache = make(map[string]int)
-	headMu    sync.Mutex
+	headCache = newLRU[int]("head")
 )
 
 var userAgents = []string{
@@ -58,7 +68,9 @@ func randomUA() string { return userAgents[rand.Intn(len(userAgents))] }
 
 func looksLikeCodeFile(p string) bool {
 	l := strings.ToLower(p)
-	return strings.HasSuffix(l, ".js") || strings.HasSuffix(l, ".mjs") || strings.HasSuffix(l, ".cjs") || strings.HasSuffix(l, ".ts")
+	return strings.HasSuffix(l, ".js") || strings.HasSuffix(l, ".mjs") || strings.HasSuffix(l, ".cjs") || strings.HasSuffix(l, ".ts") ||
+		strings.HasSuffix(l, ".tsx") || strings.HasSuffix(l, ".mts") || strings.HasSuffix(l, ".cts") ||
+		strings.HasSuffix(l, ".jsx") || strings.HasSuffix(l, ".vue") || strings.HasSuffix(l, ".svelte") || isPythonSource(p)
 }
 
 func filterManifestURLs(lines []string) []string {
@@ -87,7 +99,7 @@ func filterManifestURLs(lines []string) []string {
 			pathPlus += "?" + p.RawQuery
 		}
 		unesc, _ := url.PathUnescape(pathPlus)
-		if manifestRe.MatchString(unesc) || looksLikeCodeFile(unesc) {
+		if manifestRe.MatchString(unesc) || looksLikeCodeFile(unesc) || isSourceMap(p.Path) || (opts.html && looksLikePage(p.Path)) {
 			seen[u] = struct{}{}
 			out = append(out, u)
 		}
@@ -96,20 +108,40 @@ func filterManifestURLs(lines []string) []string {
 }
 
 func httpGET(u string, headers map[string]string) ([]byte, int, error) {
+	b, status, _, err := httpGETHeader(u, headers)
+	return b, status, err
+}
+
+func httpGETHeader(u string, headers map[string]string) ([]byte, int, http.Header, error) {
 	req, err := http.NewRequest(http.MethodGet, u, nil)
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, nil, err
 	}
+	req.Header.Set("Accept-Encoding", acceptEncoding)
 	for k, v := range headers {
 		req.Header.Set(k, v)
 	}
-	resp, err := httpClient.Do(req)
+	applyUA(req)
+	client := httpClient
+	if p := authFor(req.URL); p != nil {
+		p.apply(req)
+		if p.client != nil {
+			client = p.client
+		}
+	}
+	release := acquireHost(u)
+	defer release()
+	resp, err := client.Do(req)
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, nil, err
 	}
 	defer resp.Body.Close()
 	b, err := io.ReadAll(resp.Body)
-	return b, resp.StatusCode, err
+	if err != nil {
+		return nil, resp.StatusCode, resp.Header, err
+	}
+	b, err = decodeBody(resp.Header.Get("Content-Encoding"), b)
+	return b, resp.StatusCode, resp.Header, err
 }
 
 func httpHEAD(u string, headers map[string]string) (int, error) {
@@ -120,112 +152,315 @@ func httpHEAD(u string, headers map[string]string) (int, error) {
 	for k, v := range headers {
 		req.Header.Set(k, v)
 	}
+	applyUA(req)
 
-	headMu.Lock()
-	if st, ok := headCache[u]; ok {
-		headMu.Unlock()
+	if st, ok := headCache.get(u); ok {
 		return st, nil
 	}
-	headMu.Unlock()
 
+	release := acquireHost(u)
 	resp, err := httpClient.Do(req)
+	release()
 	if err != nil {
 		return 0, err
 	}
 	defer resp.Body.Close()
 
-	headMu.Lock()
-	headCache[u] = resp.StatusCode
-	headMu.Unlock()
+	if !lookupFailed(resp.StatusCode) {
+		headCache.add(u, resp.StatusCode, 0)
+	}
 	return resp.StatusCode, nil
 }
 
+// lookupFailed reports whether a registry answered without saying anything
+// about the package: rate limits and server errors go away on a retry.
+func lookupFailed(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
 type packageJSON struct {
-	Dependencies    map[string]string `json:"dependencies"`
-	DevDependencies map[string]string `json:"devDependencies"`
+	Dependencies          map[string]string `json:"dependencies"`
+	DevDependencies       map[string]string `json:"devDependencies"`
+	PeerDependencies      map[string]string `json:"peerDependencies"`
+	OptionalDependencies  map[string]string `json:"optionalDependencies"`
+	BundledDependencies   json.RawMessage   `json:"bundledDependencies"`
+	BundleDependencies    json.RawMessage   `json:"bundleDependencies"`
+	Overrides             json.RawMessage   `json:"overrides"`
+	Resolutions           map[string]string `json:"resolutions"`
+	PackageManager        string            `json:"packageManager"`
+	Scripts               map[string]string `json:"scripts"`
+	Engines               json.RawMessage   `json:"engines"`
+	ExtensionDependencies []string          `json:"extensionDependencies"`
+	ExtensionPack         []string          `json:"extensionPack"`
 }
 
 type language string
 
 const (
-	langJS     language = "js"
-	langPython language = "python"
+	langJS         language = "js"
+	langPython     language = "python"
+	langCocoaPods  language = "cocoapods"
+	langSwift      language = "swift"
+	langHelm       language = "helm"
+	langVSCode     language = "vscode"
+	langBrowserExt language = "browser-extension"
+	langR          language = "r"
 )
 
-func getDependencies(targetURL string) (deps []string, lang language, err error) {
-	h := map[string]string{"User-Agent": randomUA()}
-	body, _, err := httpGET(targetURL, h)
+func registryFor(lang language) string {
+	switch lang {
+	case langJS:
+		return "npm"
+	case langCocoaPods:
+		return "cocoapods"
+	case langSwift:
+		return "github"
+	case langHelm:
+		return "artifacthub"
+	case langVSCode:
+		return "vscode-marketplace"
+	case langBrowserExt:
+		return "extension-update"
+	case langR:
+		return "cran"
+	default:
+		return "pypi"
+	}
+}
+
+// dependency is a package name with the version range the source asked for
+// and the non-public registry it resolves from, when those are known.
+type dependency struct {
+	Name            string
+	Spec            string
+	PrivateRegistry string
+	// Source is the repository the dependency is pulled from, where that is
+	// declared per dependency (Helm charts)
+	Source string
+	// Lang overrides the file's language for manifests that mix ecosystems,
+	// such as the extension dependencies of a VS Code package.json
+	Lang language
+	// Via says how the package is pulled in when that is not a dependency
+	// list, e.g. npx in a script
+	Via string
+	// Type is left empty for production dependencies of manifests; names
+	// from code get depCode from checkContent
+	Type depType
+}
+
+func namesToDeps(names []string) []dependency {
+	deps := make([]dependency, 0, len(names))
+	for _, n := range names {
+		deps = append(deps, dependency{Name: n})
+	}
+	return deps
+}
+
+// targetContent is what a fetched target yields: its dependencies and any
+// further targets it points to.
+type targetContent struct {
+	deps  []dependency
+	lang  language
+	conf  confidence
+	body  []byte
+	links []string
+	// bower manifests resolve through the Bower registry before npm
+	bower bool
+}
+
+func getDependencies(targetURL string) (targetContent, error) {
+	h := targetHeaders()
+	for k, v := range capturedHeaders(targetURL) {
+		h[k] = v
+	}
+	body, status, header, err := httpGETHeader(targetURL, h)
 	if err != nil {
-		return nil, "", err
+		return targetContent{}, categorize(errNetwork, err)
+	}
+	switch status {
+	case http.StatusTooManyRequests:
+		return targetContent{}, categorize(errRateLimited, fmt.Errorf("%s returned %d", targetURL, status))
+	case http.StatusNotFound, http.StatusGone:
+		return targetContent{}, categorize(errNotFound, fmt.Errorf("%s returned %d", targetURL, status))
 	}
+	body = decodeText(body)
+	if isHTML(header.Get("Content-Type"), body) {
+		// an HTML answer for a manifest or bundle URL is a soft 404
+		if !opts.html {
+			return targetContent{}, categorize(errSoft404, fmt.Errorf("%s answered with an HTML page", targetURL))
+		}
+		deps, links := parseHTML(targetURL, body)
+		return targetContent{deps: deps, lang: langJS, conf: confMedium, body: body, links: links}, nil
+	}
+	if isSourceMap(targetURL) {
+		return parseSourceMap(targetURL, body)
+	}
+	return parseContent(targetURL, body)
+}
 
+func parseContent(name string, body []byte) (targetContent, error) {
+	body = decodeText(body)
+	deps, lang, err := parseDependencies(name, body)
+	if err != nil {
+		return targetContent{}, categorize(errParse, err)
+	}
+	return targetContent{deps: deps, lang: lang, conf: confidenceFor(name, lang), body: body, bower: isBowerManifest(name)}, nil
+}
+
+func parseDependencies(targetURL string, body []byte) (deps []dependency, lang language, err error) {
 	if strings.EqualFold(path.Base(targetURL), "package.json") {
 		var pj packageJSON
 		if err := json.Unmarshal(body, &pj); err != nil {
 			return nil, "", err
 		}
-		for k := range pj.Dependencies {
-			deps = append(deps, k)
-		}
-		for k := range pj.DevDependencies {
-			deps = append(deps, k)
-		}
-		return deps, langJS, nil
+		return append(packageJSONDeps(pj), vscodeExtensionDeps(pj)...), langJS, nil
+	}
+
+	if isBrowserExtensionManifest(targetURL) {
+		deps, err := parseBrowserExtensionManifest(body)
+		return deps, langBrowserExt, err
+	}
+
+	if isLockfile(targetURL) {
+		return parseLockfile(targetURL, body)
+	}
+
+	if isAppleManifest(targetURL) {
+		return parseAppleManifest(targetURL, body)
+	}
+
+	if isRManifest(targetURL) {
+		deps, err := parseRManifest(targetURL, body)
+		return deps, langR, err
+	}
+
+	if isHelmManifest(targetURL) {
+		return parseChartDependencies(body), langHelm, nil
+	}
+
+	if isBowerManifest(targetURL) {
+		deps, err := parseBowerManifest(body)
+		return deps, langJS, err
+	}
+
+	if isSetupManifest(targetURL) {
+		return parseSetupManifest(targetURL, body), langPython, nil
+	}
+
+	if isPythonSource(targetURL) {
+		return parsePythonImports(targetURL, body), langPython, nil
 	}
 
 	if looksLikeCodeFile(targetURL) {
 		jsDeps := extractPackagesFromJS(string(body))
 		if len(jsDeps) > 0 {
-			return jsDeps, langJS, nil
+			return namesToDeps(jsDeps), langJS, nil
 		}
 	}
 
+	var index string
 	lines := strings.Split(string(body), "\n")
 	for _, ln := range lines {
 		line := strings.TrimSpace(ln)
 		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
-		parts := reqSplitRe.Split(line, -1)
-		if len(parts) > 0 {
-			pkg := strings.TrimSpace(parts[0])
-			if pkg != "" {
-				deps = append(deps, pkg)
+		if strings.HasPrefix(line, "-") {
+			fields := strings.Fields(strings.ReplaceAll(line, "=", " "))
+			if len(fields) > 1 {
+				switch fields[0] {
+				case "-i", "--index-url", "--extra-index-url":
+					if h := privateRegistryHost(fields[1]); h != "" && index == "" {
+						index = h
+					}
+				}
 			}
+			continue
+		}
+		if d, ok := requirementDep(line); ok {
+			deps = append(deps, d)
 		}
 	}
+	typ := requirementsType(targetURL)
+	for i := range deps {
+		deps[i].PrivateRegistry = index
+		deps[i].Type = typ
+	}
 	return deps, langPython, nil
 }
 
-func extractPackagesFromJS(content string) []string {
-	set := map[string]struct{}{}
-
-	for _, m := range scopedRe.FindAllString(content, -1) {
-		if strings.HasPrefix(m, ".") || strings.HasPrefix(m, "/") {
-			continue
+// requirementsType reads requirements-dev.txt, dev-requirements.txt,
+// requirements/test.txt and the like as dev dependencies.
+func requirementsType(targetURL string) depType {
+	if looksLikeCodeFile(targetURL) {
+		return ""
+	}
+	file := strings.ToLower(path.Base(strings.SplitN(targetURL, "?", 2)[0]))
+	for _, w := range []string{"dev", "test", "lint", "docs"} {
+		if strings.Contains(file, w) {
+			return depDev
 		}
-		set[m] = struct{}{}
 	}
+	return ""
+}
 
-	for _, sub := range importReqRe.FindAllStringSubmatch(content, -1) {
-		var pkg string
-		if sub[1] != "" {
-			pkg = sub[1]
-		} else if sub[2] != "" {
-			pkg = sub[2]
-		}
-		pkg = strings.TrimSpace(pkg)
-		if pkg == "" {
-			continue
+// requirementDep splits a PEP 508 requirement such as "foo[bar]>=1.0; python_version<'3'".
+func requirementDep(line string) (dependency, bool) {
+	parts := reqSplitRe.Split(line, -1)
+	pkg := strings.TrimSpace(parts[0])
+	if pkg == "" {
+		return dependency{}, false
+	}
+	spec := strings.TrimSpace(strings.TrimPrefix(line, parts[0]))
+	if i := strings.IndexByte(spec, ';'); i >= 0 {
+		spec = strings.TrimSpace(spec[:i])
+	}
+	// extras pick optional dependencies, not versions
+	if strings.HasPrefix(spec, "[") {
+		if i := strings.IndexByte(spec, ']'); i >= 0 {
+			spec = strings.TrimSpace(spec[i+1:])
 		}
-		if strings.HasPrefix(pkg, ".") || strings.HasPrefix(pkg, "/") {
-			continue
+	}
+	// a direct reference ("name @ git+https://...") never reaches the index
+	if strings.Contains(spec, "@") {
+		return dependency{}, false
+	}
+	return dependency{Name: pkg, Spec: spec}, true
+}
+
+func extractPackagesFromJS(content string) []string {
+	set := map[string]struct{}{}
+
+	chunks := scanChunks(content)
+	for i, chunk := range chunks {
+		for _, loc := range scopedRe.FindAllStringIndex(chunk, -1) {
+			// cut off by the window; the next one has it whole
+			if loc[1] == len(chunk) && i < len(chunks)-1 {
+				continue
+			}
+			set[chunk[loc[0]:loc[1]]] = struct{}{}
 		}
-		lpkg := strings.ToLower(pkg)
-		if strings.HasPrefix(lpkg, "http://") || strings.HasPrefix(lpkg, "https://") || strings.HasPrefix(lpkg, "git+") {
-			continue
+
+		for _, sub := range importReqRe.FindAllStringSubmatch(chunk, -1) {
+			var pkg string
+			if sub[1] != "" {
+				pkg = sub[1]
+			} else if sub[2] != "" {
+				pkg = sub[2]
+			}
+			pkg = strings.TrimSpace(pkg)
+			if pkg == "" {
+				continue
+			}
+			if strings.HasPrefix(pkg, ".") || strings.HasPrefix(pkg, "/") {
+				continue
+			}
+			lpkg := strings.ToLower(pkg)
+			if strings.HasPrefix(lpkg, "http://") || strings.HasPrefix(lpkg, "https://") || strings.HasPrefix(lpkg, "git+") {
+				continue
+			}
+			set[pkg] = struct{}{}
 		}
-		set[pkg] = struct{}{}
 	}
 
 	out := make([]string, 0, len(set))
@@ -236,39 +471,285 @@ func extractPackagesFromJS(content string) []string {
 	return out
 }
 
+// depType is how the target uses a package: production dependencies run
+// everywhere, dev dependencies on developer and CI machines, and names
+// scraped from code may not be packages at all.
+type depType string
+
+const (
+	depProd depType = "prod"
+	depDev  depType = "dev"
+	depCode depType = "code"
+)
+
+var depTypeOrder = map[depType]int{depProd: 0, depDev: 1, depCode: 2}
+
+// allowedTypes is set by -types; nil checks every type.
+var allowedTypes map[depType]bool
+
+// parseTypes parses a -types list such as "prod,dev".
+func parseTypes(s string) (map[depType]bool, error) {
+	types := make(map[depType]bool)
+	for _, t := range strings.Split(s, ",") {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		if _, ok := depTypeOrder[depType(t)]; !ok {
+			return nil, fmt.Errorf("unknown dependency type %q (want prod, dev or code)", t)
+		}
+		types[depType(t)] = true
+	}
+	return types, nil
+}
+
+type confidence string
+
+const (
+	confHigh   confidence = "high"
+	confMedium confidence = "medium"
+	confLow    confidence = "low"
+)
+
+// confidenceFor rates how much a dependency list can be trusted: names from a
+// manifest are real, names scraped from code may be noise, and a code file
+// that fell through to line parsing is mostly guesswork, as are the imports
+// of a Python file, which may name modules of the project itself.
+func confidenceFor(targetURL string, lang language) confidence {
+	if strings.EqualFold(path.Base(targetURL), "package.json") || !looksLikeCodeFile(targetURL) {
+		return confHigh
+	}
+	if lang == langJS {
+		return confMedium
+	}
+	return confLow
+}
+
+func findSnippet(body, pkg string) string {
+	const ctx = 80
+	idx := strings.Index(body, pkg)
+	if idx < 0 {
+		return ""
+	}
+	start := strings.LastIndexByte(body[:idx], '\n') + 1
+	if idx-start > ctx {
+		start = idx - ctx
+	}
+	end := len(body)
+	if nl := strings.IndexByte(body[idx:], '\n'); nl >= 0 {
+		end = idx + nl
+	}
+	if end-(idx+len(pkg)) > ctx {
+		end = idx + len(pkg) + ctx
+	}
+	return strings.TrimSpace(strings.ToValidUTF8(body[start:end], ""))
+}
+
+type findingKind string
+
+const (
+	kindUnclaimed      findingKind = "unclaimed"
+	kindSecurityHolder findingKind = "security-holder"
+	kindPlaceholder    findingKind = "placeholder"
+	kindUnpublished    findingKind = "unpublished"
+	kindVersionGap     findingKind = "version-gap"
+	kindRepojack       findingKind = "repojack"
+	kindDeadRepo       findingKind = "dead-repo"
+	kindTransitive     findingKind = "transitive"
+)
+
 type vuln struct {
-	Package  string
-	Status   int
-	Language language
+	Package         string      `json:"package"`
+	Status          int         `json:"status"`
+	Language        language    `json:"language"`
+	Kind            findingKind `json:"kind"`
+	Detail          string      `json:"detail,omitempty"`
+	PrivateRegistry string      `json:"private_registry,omitempty"`
+	Confidence      confidence  `json:"confidence"`
+	Type            depType     `json:"type,omitempty"`
+	Snippet         string      `json:"snippet,omitempty"`
+	Corroboration   string      `json:"corroboration,omitempty"`
+	Keyword         string      `json:"keyword,omitempty"`
 }
 
-func isUnclaimed(pkg string, lang language) (bool, int) {
-	var checkURL string
+func (v vuln) title() string {
+	reg := registryFor(v.Language)
+	switch v.Kind {
+	case kindSecurityHolder:
+		return fmt.Sprintf("%s security holder package %s", reg, v.Package)
+	case kindPlaceholder:
+		return fmt.Sprintf("Placeholder %s package %s", reg, v.Package)
+	case kindUnpublished:
+		return fmt.Sprintf("Unpublished %s package %s", reg, v.Package)
+	case kindVersionGap:
+		return fmt.Sprintf("Version gap on %s package %s", reg, v.Package)
+	case kindTransitive:
+		return fmt.Sprintf("Unclaimed transitive npm dependency %s", v.Package)
+	case kindDeadRepo:
+		if v.Language == langBrowserExt {
+			return fmt.Sprintf("Dead browser extension update URL %s", v.Package)
+		}
+		return fmt.Sprintf("Dead chart repository for %s", v.Package)
+	case kindRepojack:
+		if v.Language == langSwift {
+			return fmt.Sprintf("Repojackable Swift package github.com/%s", v.Package)
+		}
+		if isGitHubRemote(v.Package, v.Language) {
+			return fmt.Sprintf("Repojackable R remote github.com/%s", v.Package)
+		}
+		return fmt.Sprintf("Repojackable repository of %s package %s", reg, v.Package)
+	default:
+		if v.Language == langVSCode {
+			return fmt.Sprintf("Unclaimed VS Code extension %s", v.Package)
+		}
+		return fmt.Sprintf("Unclaimed %s package %s", reg, v.Package)
+	}
+}
+
+func (v vuln) summary() string {
+	s := v.kindSummary()
+	if v.PrivateRegistry != "" {
+		s += fmt.Sprintf(" The target resolves it from the private registry %s.", v.PrivateRegistry)
+	}
+	if v.Corroboration != "" {
+		s += " " + v.Corroboration
+	}
+	return s
+}
+
+func (v vuln) kindSummary() string {
+	reg := registryFor(v.Language)
+	switch v.Kind {
+	case kindSecurityHolder:
+		return fmt.Sprintf("The %s package %s is referenced by the target but the name is held by the npm security team, "+
+			"usually after a malicious version was removed. The dependency cannot be installed from the public registry.", reg, v.Package)
+	case kindPlaceholder:
+		return fmt.Sprintf("The %s package %s is referenced by the target but exists on the public registry without any published version.",
+			reg, v.Package)
+	case kindUnpublished:
+		return fmt.Sprintf("The %s package %s is referenced by the target and used to exist on the public registry but was removed (%s). "+
+			"Removed names can become available for anyone to register, which opens a takeover window.", reg, v.Package, v.Detail)
+	case kindVersionGap:
+		return fmt.Sprintf("The target requests a version of %s that no public %s release satisfies (%s), so it is resolved from a private registry. "+
+			"Whoever owns the public name can publish a higher matching version and win resolution in mixed-registry setups.", v.Package, reg, v.Detail)
+	case kindTransitive:
+		return transitiveSummary(v)
+	case kindDeadRepo:
+		if v.Language == langBrowserExt {
+			return fmt.Sprintf("The browser extension fetches its updates from %s, which no longer exists (%s). "+
+				"Whoever takes over the domain or bucket can push an update to every installed copy of the extension.", v.Package, v.Detail)
+		}
+		return fmt.Sprintf("The Helm chart %s is pulled from a repository that no longer exists (%s). "+
+			"Whoever takes over the domain or bucket can serve a chart of that name to every install and upgrade.", v.Package, v.Detail)
+	case kindRepojack:
+		if v.Language == langSwift {
+			return fmt.Sprintf("The target depends on the Swift package github.com/%s, whose repository and GitHub owner no longer exist. "+
+				"Anyone can register the owner name, recreate the repository and have their code built into the target.", v.Package)
+		}
+		if isGitHubRemote(v.Package, v.Language) {
+			return fmt.Sprintf("The target installs the R package remote github.com/%s, whose repository and GitHub owner no longer exist. "+
+				"Anyone can register the owner name, recreate the repository and have their code installed by remotes or renv.", v.Package)
+		}
+		return fmt.Sprintf("The %s package %s is registered, but its metadata points to a repository whose GitHub owner no longer exists (%s). "+
+			"Anyone can register the owner name and serve code from that repository to users and tools that trust the package's source link.", reg, v.Package, v.Detail)
+	default:
+		if v.Language == langVSCode {
+			return fmt.Sprintf("The VS Code extension %s is installed as a dependency of the target's extension but is not published (%s). "+
+				"Anyone can register the publisher and ship an extension under that ID, which VS Code installs alongside the target's.", v.Package, v.Detail)
+		}
+		if v.Language == langJS && strings.HasPrefix(v.Detail, "scope @") && !strings.HasSuffix(v.Detail, npmScopeFree) {
+			return fmt.Sprintf("The %s package %s is referenced by the target but is not registered on the public registry (HTTP %d). "+
+				"Only members of its scope can publish it: the %s. Check whether the scope belongs to the target or to a squatter.", reg, v.Package, v.Status, v.Detail)
+		}
+		return fmt.Sprintf("The %s package %s is referenced by the target but is not registered on the public registry (HTTP %d). "+
+			"Anyone can publish it and have it installed by builds that resolve against the public registry.", reg, v.Package, v.Status)
+	}
+}
+
+func registryURL(pkg string, lang language) string {
 	switch lang {
 	case langJS:
-		checkURL = fmt.Sprintf(npmURL, pkg)
+		return fmt.Sprintf(npmURL, pkg)
+	case langCocoaPods:
+		return fmt.Sprintf(cocoapodsURL, pkg)
+	case langSwift:
+		return githubWebURL + "/" + pkg
+	case langHelm:
+		return fmt.Sprintf(artifactHubURL, url.QueryEscape(pkg))
+	case langVSCode:
+		return fmt.Sprintf(vscodeItemURL, url.QueryEscape(pkg))
+	case langBrowserExt:
+		return pkg
+	case langR:
+		if isGitHubRemote(pkg, lang) {
+			return githubWebURL + "/" + pkg
+		}
+		return fmt.Sprintf(cranURL, pkg)
 	default:
-		checkURL = fmt.Sprintf(pypiURL, pkg)
+		return fmt.Sprintf(pypiURL, pkg)
 	}
+}
 
-	headMu.Lock()
-	if st, ok := headCache[checkURL]; ok {
-		headMu.Unlock()
-		if st != http.StatusOK && st != http.StatusFound {
-			return true, st
+func isUnclaimed(pkg string, lang language) (bool, int) {
+	r := packageLookups.do(lookupKey(pkg, lang), func() (packageStatus, int) {
+		reg := registryFor(lang)
+		metricRegistryReqs.inc(reg)
+		status, err := httpHEAD(registryURL(pkg, lang), map[string]string{"User-Agent": randomUA()})
+		if err != nil {
+			metricRegistryErrors.inc(reg)
+			return packageStatus{}, 0
+		}
+		if lookupFailed(status) {
+			metricRegistryErrors.inc(reg)
+			return packageStatus{status: status}, 0
 		}
-		return false, st
+		return packageStatus{unclaimed: status != http.StatusOK && status != http.StatusFound, status: status}, 0
+	})
+	if !shareable(r) {
+		// only a conclusive answer is kept; ask again next time
+		packageLookups.forget(lookupKey(pkg, lang))
 	}
-	headMu.Unlock()
+	return r.unclaimed, r.status
+}
 
-	status, err := httpHEAD(checkURL, map[string]string{"User-Agent": randomUA()})
-	if err != nil {
-		return false, 0
+func checkPackage(pkg, spec string, lang language) (kind findingKind, status int, detail string) {
+	isV, code := isUnclaimed(pkg, lang)
+	if lang == langSwift || isGitHubRemote(pkg, lang) {
+		if isV {
+			k, d := githubRemoteRepojackKind(pkg)
+			return k, code, d
+		}
+		return "", code, ""
+	}
+	if lang == langR && isV && bioconductorRegistered(pkg) {
+		return "", code, ""
+	}
+	if opts.meta {
+		switch {
+		case lang == langJS && (isV || code == http.StatusOK):
+			if k, d := npmMetaKind(pkg); k != "" {
+				return k, code, d
+			}
+		case lang == langPython && code == http.StatusOK:
+			if k, d := pypiMetaKind(pkg); k != "" {
+				return k, code, d
+			}
+		}
+		if !isV && code == http.StatusOK && spec != "" && lang != langCocoaPods {
+			if gap, d := versionGap(pkg, spec, lang); gap {
+				return kindVersionGap, code, d
+			}
+		}
 	}
-	if status != http.StatusOK && status != http.StatusFound {
-		return true, status
+	if opts.repoCheck && !isV && code == http.StatusOK && lang != langCocoaPods {
+		if k, d := repojackKind(pkg, lang); k != "" {
+			return k, code, d
+		}
+	}
+	if isV {
+		return kindUnclaimed, code, ""
 	}
-	return false, status
+	return "", code, ""
 }
 
 func runWorkers[T any, R any](inputs []T, worker func(T) (R, error), concurrency int) ([]R, error) {
@@ -302,7 +783,7 @@ func runWorkers[T any, R any](inputs []T, worker func(T) (R, error), concurrency
 			defer wg.Done()
 			for it := range inCh {
 				sem <- struct{}{}
-				val, err := worker(it.t)
+				val, err := callWorker(worker, it.t)
 				<-sem
 				outCh <- out{i: it.i, val: val, err: err}
 			}
@@ -325,49 +806,124 @@ func runWorkers[T any, R any](inputs []T, worker func(T) (R, error), concurrency
 	return results, firstErr
 }
 
-func checkURLDependencies(targetURL string, threads int) ([]vuln, error) {
-	deps, lang, err := getDependencies(targetURL)
+func checkURLDependencies(targetURL string, threads int) ([]vuln, []string, error) {
+	tc, err := getDependencies(targetURL)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	return checkContent(tc, threads), tc.links, nil
+}
+
+func checkContent(tc targetContent, threads int) []vuln {
+	deps, lang, conf, body := tc.deps, tc.lang, tc.conf, tc.body
 	if len(deps) == 0 {
-		return nil, nil
+		return nil
 	}
 
-	type inp struct{ name string }
-	type outp struct{ v *vuln }
+	type inp struct {
+		name, spec, registry, source, via string
+		lang                              language
+		typ                               depType
+	}
+	type outp struct {
+		v *vuln
+		// set for registered npm packages, the roots of -transitive
+		claimed *transitiveDep
+	}
 
 	inputs := make([]inp, 0, len(deps))
 	seen := make(map[string]struct{})
+	idx := make(map[string]int)
 	for _, d := range deps {
-		d = strings.TrimSpace(d)
-		if d == "" {
+		name := strings.TrimSpace(d.Name)
+		if name == "" {
 			continue
 		}
-		if _, ok := seen[d]; ok {
+		typ := d.Type
+		if typ == "" {
+			typ = depProd
+			if conf != confHigh {
+				typ = depCode
+			}
+		}
+		if _, ok := seen[name]; ok {
+			// a name listed twice counts as its most important use
+			if i, ok := idx[name]; ok && depTypeOrder[typ] < depTypeOrder[inputs[i].typ] {
+				inputs[i].typ = typ
+			}
+			continue
+		}
+		seen[name] = struct{}{}
+		l := lang
+		if d.Lang != "" {
+			l = d.Lang
+		}
+		if conf != confHigh && !opts.keepPopular && isPopular(name, l) {
 			continue
 		}
-		seen[d] = struct{}{}
-		inputs = append(inputs, inp{name: d})
+		idx[name] = len(inputs)
+		inputs = append(inputs, inp{name: name, spec: d.Spec, registry: d.PrivateRegistry, source: d.Source, via: d.Via, lang: l, typ: typ})
+	}
+	if allowedTypes != nil {
+		kept := inputs[:0]
+		for _, x := range inputs {
+			if allowedTypes[x.typ] {
+				kept = append(kept, x)
+			}
+		}
+		inputs = kept
 	}
 
 	worker := func(x inp) (outp, error) {
-		isV, code := isUnclaimed(x.name, lang)
-		if isV {
-			return outp{v: &vuln{Package: x.name, Status: code, Language: lang}}, nil
+		metricPackages.inc(registryFor(x.lang))
+		var kind findingKind
+		var code int
+		var detail string
+		switch x.lang {
+		case langHelm:
+			kind, code, detail = checkChart(x.name, x.source)
+		case langVSCode:
+			kind, code, detail = checkExtension(x.name)
+		case langBrowserExt:
+			kind, code, detail = checkUpdateURL(x.source)
+		default:
+			kind, code, detail = checkPackage(x.name, x.spec, x.lang)
+		}
+		if kind == kindUnclaimed && tc.bower && bowerRegistered(x.name) {
+			kind = ""
 		}
-		return outp{v: nil}, nil
+		if opts.scopeCheck && kind == kindUnclaimed && x.lang == langJS && detail == "" {
+			detail = npmScopeDetail(x.name)
+		}
+		if detail == "" {
+			detail = x.via
+		}
+		if kind != "" {
+			return outp{v: &vuln{Package: x.name, Status: code, Language: x.lang, Kind: kind, Detail: detail, PrivateRegistry: x.registry, Confidence: conf, Type: x.typ, Snippet: findSnippet(string(body), x.name)}}, nil
+		}
+		if x.lang == langJS && code == http.StatusOK {
+			return outp{claimed: &transitiveDep{name: x.name, spec: x.spec, path: []string{x.name}, typ: x.typ}}, nil
+		}
+		return outp{}, nil
 	}
 
 	outs, _ := runWorkers(inputs, worker, threads)
 
 	var vulns []vuln
+	var claimed []transitiveDep
+	// outs come in completion order, so each carries its own input
 	for _, o := range outs {
 		if o.v != nil {
 			vulns = append(vulns, *o.v)
 		}
+		if o.claimed != nil {
+			claimed = append(claimed, *o.claimed)
+		}
+	}
+	if opts.transitive && len(claimed) > 0 {
+		vulns = append(vulns, checkTransitive(claimed, seen, conf, threads)...)
 	}
-	return vulns, nil
+	return vulns
 }
 
 func printBanner() {
@@ -382,65 +938,579 @@ func printBanner() {
  |___/ \___|_||_|___|_|_\ \___/  
 
 `
-	fmt.Printf("%s%s%s", red, banner, reset)
+	if useColor(os.Stderr) {
+		fmt.Fprintf(os.Stderr, "%s%s%s", ansiRed, banner, ansiReset)
+		return
+	}
+	fmt.Fprint(os.Stderr, banner)
 }
 
-func main() {
-	silent := flag.Bool("silent", false, "suppress banner output")
-	threads := flag.Int("t", 20, "number of threads (1-100)")
-	flag.Parse()
+// useColor reports whether ANSI colors go to f: not disabled by -no-color or
+// NO_COLOR, and f is a terminal rather than a pipe or file.
+func useColor(f *os.File) bool {
+	if opts.noColor || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
 
-	if *threads < 1 {
-		*threads = 1
+func scanAll(urls []string) []scanResult {
+	var results []scanResult
+	if fleet != nil {
+		results = fleet.scan(urls)
+	} else {
+		results = scanURLs(urls, opts.threads)
 	}
-	if *threads > 100 {
-		*threads = 100
+	results = append(results, scanRepositories(opts.threads)...)
+	rateFindings(results)
+	return results
+}
+
+// rateFindings adjusts the confidence of findings by -keywords and, with
+// -github-search, by who else references them.
+func rateFindings(results []scanResult) {
+	applyKeywords(results)
+	if opts.githubSearch {
+		corroborateGitHub(results)
 	}
+}
 
-	if !*silent {
-		printBanner()
+type scanResult struct {
+	u     string
+	vulns []vuln
+	links []string
+	err   error
+}
+
+// scanURLs scans the targets (or, with -probe, what probing them finds) and then, wave by wave, the new targets they
+// link to (script tags of HTML pages).
+func scanURLs(urls []string, threads int) []scanResult {
+	type inp struct{ u string }
+	worker := func(x inp) (scanResult, error) {
+		return safeScan(x.u, func() scanResult {
+			vv, links, err := checkURLDependencies(x.u, threads)
+			return scanResult{u: x.u, vulns: vv, links: links, err: err}
+		}), nil
 	}
 
-	var raw []string
-	sc := bufio.NewScanner(os.Stdin)
+	urls = expandBuckets(urls)
+	if opts.probe {
+		manifests, bases := splitProbeInputs(urls)
+		urls = append(manifests, probeTargets(bases, threads)...)
+	}
+
+	var all []scanResult
+	seen := make(map[string]struct{})
+	queue := filterManifestURLs(urls)
+	for len(queue) > 0 {
+		inputs := make([]inp, 0, len(queue))
+		for _, u := range queue {
+			seen[u] = struct{}{}
+			inputs = append(inputs, inp{u: u})
+		}
+		results, _ := runWorkers(inputs, worker, threads)
+		all = append(all, results...)
+
+		var next []string
+		for _, r := range results {
+			for _, l := range r.links {
+				if _, ok := seen[l]; !ok {
+					next = append(next, l)
+				}
+			}
+		}
+		queue = filterManifestURLs(next)
+	}
+	return all
+}
+
+func printVuln(v vuln, u string) {
+	if opts.nuclei {
+		printNuclei(v, u)
+		return
+	}
+	if outputFormat != nil {
+		printFormatted(v, []string{u})
+		return
+	}
+	fields := []string{v.Package, strconv.Itoa(v.Status), string(v.Language)}
+	if v.Kind != "" && v.Kind != kindUnclaimed {
+		fields = append(fields, string(v.Kind))
+	}
+	if v.PrivateRegistry != "" {
+		fields = append(fields, "registry="+v.PrivateRegistry)
+	}
+	if v.Type != "" && v.Type != depProd {
+		fields = append(fields, "type="+string(v.Type))
+	}
+	if v.Keyword != "" {
+		fields = append(fields, "keyword="+v.Keyword)
+	}
+	tag := fmt.Sprintf("%s[%s]%s", red, strings.Join(fields, "|"), reset)
+	fmt.Printf("%s %s\n", tag, u)
+}
+
+func printResults(results []scanResult) {
+	if opts.json {
+		printJSON(results)
+		return
+	}
+	if !opts.noGroup && !opts.nuclei {
+		groups := groupFindings(results)
+		// brand keyword matches first
+		sort.SliceStable(groups, func(i, j int) bool { return groups[i].Keyword != "" && groups[j].Keyword == "" })
+		for _, g := range groups {
+			if outputFormat != nil {
+				printFormatted(g.vuln, g.URLs)
+				continue
+			}
+			printVuln(g.vuln, g.URLs[0])
+			for _, u := range g.URLs[1:] {
+				fmt.Printf("    %s\n", u)
+			}
+		}
+		return
+	}
+	for _, r := range results {
+		if r.err != nil {
+			continue
+		}
+		for _, v := range r.vulns {
+			printVuln(v, r.u)
+		}
+	}
+}
+
+func readLines(r io.Reader) []string {
+	var out []string
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
 	for sc.Scan() {
 		line := strings.TrimSpace(sc.Text())
 		if line != "" {
-			raw = append(raw, line)
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+// readURLList reads a target list from a file, or from stdin for "-".
+// gzip-compressed lists are detected by their magic bytes.
+func readURLList(name string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
 		}
+		defer zr.Close()
+		return readLines(zr), nil
 	}
-	if len(raw) == 0 {
+	return readLines(br), nil
+}
+
+// listFlag is a string flag that can be given more than once.
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, ",") }
+
+func (l *listFlag) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+type options struct {
+	threads  int
+	daemon   bool
+	interval time.Duration
+	perHost  int
+	delay    time.Duration
+	silent   bool
+	noColor  bool
+	profile  string
+
+	ua           string
+	uaFile       string
+	registryUA   string
+	headerJitter bool
+
+	list    listFlag
+	webhook string
+	metrics string
+	db      string
+	report  string
+	format  string
+	json    bool
+
+	stdinManifest bool
+	manifestType  string
+
+	types string
+
+	coordinator string
+	shardSize   int
+	join        string
+	fleetToken  string
+
+	dojoURL        string
+	dojoToken      string
+	dojoEngagement int
+	dojoProduct    string
+	exportURL      string
+
+	runReport string
+
+	nuclei          bool
+	nucleiTemplates string
+	noGroup         bool
+	meta            bool
+	html            bool
+	probe           bool
+	har             string
+	burp            string
+
+	gitlab         string
+	gitlabURL      string
+	gitlabToken    string
+	bitbucket      string
+	bitbucketURL   string
+	bitbucketToken string
+
+	cacheEntries int
+	cacheMem     sizeFlag
+	cache        string
+	cacheTTL     time.Duration
+	resolver     string
+	hosts        string
+
+	allowCrossOriginMaps bool
+	auth                 string
+
+	githubSearch bool
+	githubToken  string
+	githubOwners string
+	repoCheck    bool
+	scopeCheck   bool
+
+	transitive bool
+	depth      int
+
+	keywords     string
+	onlyKeywords bool
+
+	popularFile string
+	keepPopular bool
+}
+
+var opts options
+
+func main() {
+	if !useColor(os.Stdout) {
+		red, reset = "", ""
+	}
+	worker := false
+	if len(os.Args) > 1 {
+		var run func([]string) error
+		switch os.Args[1] {
+		case "poc":
+			run = runPoC
+		case "callbacks":
+			run = runCallbacks
+		case "popular":
+			run = runPopular
+		case "verify":
+			run = runVerify
+		}
+		if run != nil {
+			if err := run(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", os.Args[1], err)
+				os.Exit(1)
+			}
+			return
+		}
+		// workers take every scan flag, so they share the main flag set
+		if os.Args[1] == "worker" {
+			os.Args = append(os.Args[:1], os.Args[2:]...)
+			worker = true
+		}
+	}
+
+	flag.BoolVar(&opts.silent, "silent", false, "suppress banner output and cache stats")
+	flag.BoolVar(&opts.noColor, "no-color", false, "disable ANSI colors (also NO_COLOR, and automatic when stdout is not a terminal)")
+	flag.IntVar(&opts.threads, "t", 20, "number of threads (1-100)")
+	flag.StringVar(&opts.ua, "ua", "", "User-Agent for every target request instead of the rotation")
+	flag.StringVar(&opts.uaFile, "ua-file", "", "file with User-Agents to rotate through, one per line")
+	flag.StringVar(&opts.registryUA, "registry-ua", "", "User-Agent for registry requests; tool sends dchero's own (default: the rotation, never -ua)")
+	flag.BoolVar(&opts.headerJitter, "header-jitter", false, "vary Accept, Accept-Language and cache headers per target request")
+	flag.StringVar(&opts.profile, "profile", "", "preset flag combination: "+profileNames()+" (explicit flags win)")
+	flag.IntVar(&opts.perHost, "per-host", 0, "maximum concurrent requests per target host (0 = unlimited)")
+	flag.DurationVar(&opts.delay, "delay", 0, "minimum delay between requests to the same target host")
+	flag.BoolVar(&opts.daemon, "daemon", false, "keep rescanning targets and only report changes")
+	flag.DurationVar(&opts.interval, "interval", 6*time.Hour, "time between rescans in daemon mode")
+	flag.Var(&opts.list, "l", "file with target URLs, gzip allowed, - for stdin (repeatable, re-read every round in daemon mode)")
+	flag.BoolVar(&opts.stdinManifest, "stdin-manifest", false, "read raw manifest content instead of URLs from stdin")
+	flag.StringVar(&opts.manifestType, "type", "", "file name to parse -stdin-manifest content as, e.g. yarn.lock (default: guessed)")
+	flag.StringVar(&opts.har, "har", "", "read target URLs and their request headers from a HAR file")
+	flag.StringVar(&opts.burp, "burp", "", "read target URLs and their request headers from a Burp XML export")
+	flag.StringVar(&opts.gitlab, "gitlab", "", "comma-separated GitLab groups whose projects are scanned")
+	flag.StringVar(&opts.gitlabURL, "gitlab-url", "https://gitlab.com", "GitLab base URL")
+	flag.StringVar(&opts.gitlabToken, "gitlab-token", os.Getenv("GITLAB_TOKEN"), "GitLab access token (default $GITLAB_TOKEN)")
+	flag.StringVar(&opts.bitbucket, "bitbucket", "", "comma-separated Bitbucket workspaces (Cloud) or project keys (Server)")
+	flag.StringVar(&opts.bitbucketURL, "bitbucket-url", "https://api.bitbucket.org", "Bitbucket API URL (Cloud) or base URL (Server/Data Center)")
+	flag.StringVar(&opts.bitbucketToken, "bitbucket-token", os.Getenv("BITBUCKET_TOKEN"), "Bitbucket access token or user:app-password (default $BITBUCKET_TOKEN)")
+	flag.StringVar(&opts.metrics, "metrics-addr", "", "address to serve Prometheus /metrics on in daemon mode, e.g. :9090")
+	flag.StringVar(&opts.types, "types", "", "only check these dependency types: prod, dev, code (default: all)")
+	flag.StringVar(&opts.coordinator, "coordinator", "", "address to hand URL batches to dchero workers on, e.g. :8700")
+	flag.IntVar(&opts.shardSize, "shard-size", 50, "URLs per batch handed to a worker")
+	flag.StringVar(&opts.join, "join", "", "coordinator to take URL batches from (dchero worker -join host:8700)")
+	flag.StringVar(&opts.fleetToken, "fleet-token", "", "shared secret between coordinator and workers")
+	flag.StringVar(&opts.webhook, "webhook", "", "URL to POST JSON deltas to in daemon mode")
+	flag.BoolVar(&opts.probe, "probe", false, "treat input as base URLs and probe common manifest and bundle paths")
+	flag.BoolVar(&opts.html, "html", false, "accept HTML pages: scan inline scripts and follow script tags")
+	flag.BoolVar(&opts.meta, "meta", false, "fetch registry metadata for claimed packages (npm security holders, placeholders)")
+	flag.StringVar(&opts.db, "db", "", "SQLite database to record findings history in")
+	flag.StringVar(&opts.report, "report", "", "write an HTML (.html) or Markdown (.md) report to this file")
+	flag.StringVar(&opts.dojoURL, "dojo-url", "", "DefectDojo base URL to push findings to")
+	flag.StringVar(&opts.dojoToken, "dojo-token", os.Getenv("DOJO_TOKEN"), "DefectDojo API token (default $DOJO_TOKEN)")
+	flag.IntVar(&opts.dojoEngagement, "dojo-engagement", 0, "DefectDojo engagement ID")
+	flag.StringVar(&opts.dojoProduct, "dojo-product", "", "DefectDojo product to import into, created with a DCHero engagement if missing")
+	flag.StringVar(&opts.exportURL, "export-url", "", "URL to POST grouped findings as JSON to")
+	flag.BoolVar(&opts.nuclei, "nuclei", false, "print findings as nuclei JSONL results")
+	flag.BoolVar(&opts.json, "json", false, "print one JSON object per URL with its findings or categorized error")
+	flag.StringVar(&opts.format, "format", "", "Go text/template for finding lines, e.g. '{{.Package}} {{.URL}} {{.Status}}'")
+	flag.BoolVar(&opts.noGroup, "no-group", false, "print one line per URL instead of grouping findings by package")
+	flag.StringVar(&opts.runReport, "run-report", "", "write a JSON manifest of the run (version, flags, stats, findings, errors) to this file")
+	flag.StringVar(&opts.nucleiTemplates, "nuclei-templates", "", "directory to write a nuclei verification template per finding")
+	flag.IntVar(&opts.cacheEntries, "cache-entries", 100000, "maximum entries per registry cache (0 = unlimited)")
+	opts.cacheMem = 256 << 20
+	flag.Var(&opts.cacheMem, "cache-mem", "maximum estimated memory per registry cache, e.g. 512MB (0 = unlimited)")
+	flag.StringVar(&opts.cache, "cache", "", "shared package cache for scanning fleets, e.g. redis://:password@host:6379/0")
+	flag.DurationVar(&opts.cacheTTL, "cache-ttl", 24*time.Hour, "how long package results live in the shared -cache")
+	flag.StringVar(&opts.resolver, "resolver", "", "DNS server to resolve hosts with, e.g. 1.1.1.1:53 (default system resolver)")
+	flag.StringVar(&opts.keywords, "keywords", "", "comma-separated target brand keywords; matching package names are marked keyword=")
+	flag.BoolVar(&opts.onlyKeywords, "only-keywords", false, "only report packages matching -keywords")
+	flag.StringVar(&opts.popularFile, "popular-file", defaultPopularFile(), "top npm/PyPI package list written by the popular subcommand")
+	flag.BoolVar(&opts.keepPopular, "keep-popular", false, "also check popular package names scraped from code")
+	flag.BoolVar(&opts.transitive, "transitive", false, "walk the npm dependencies of claimed packages for unclaimed transitive names")
+	flag.IntVar(&opts.depth, "depth", 2, "levels of dependencies to walk with -transitive")
+	flag.BoolVar(&opts.repoCheck, "repo-check", false, "flag claimed packages whose GitHub repository owner no longer exists (repojacking)")
+	flag.BoolVar(&opts.scopeCheck, "scope-check", false, "describe who holds the npm scope of unclaimed scoped packages")
+	flag.BoolVar(&opts.githubSearch, "github-search", false, "rate unclaimed findings by GitHub code search references outside the target")
+	flag.StringVar(&opts.githubToken, "github-token", os.Getenv("GITHUB_TOKEN"), "GitHub token for code search (default $GITHUB_TOKEN)")
+	flag.StringVar(&opts.githubOwners, "github-owners", "", "comma-separated GitHub users/orgs of the target, not counted as outside references")
+	flag.StringVar(&opts.auth, "auth", "", "JSON file mapping target host patterns to credentials")
+	flag.BoolVar(&opts.allowCrossOriginMaps, "allow-cross-origin-maps", false, "also fetch sourcemap sources hosted on other origins")
+	flag.StringVar(&opts.hosts, "hosts", "", "file mapping hosts to IPs in /etc/hosts format, checked before DNS")
+	flag.Parse()
+
+	if opts.uaFile != "" {
+		if err := loadUserAgents(opts.uaFile); err != nil {
+			fmt.Fprintf(os.Stderr, "error reading %s: %v\n", opts.uaFile, err)
+			os.Exit(1)
+		}
+	}
+	if opts.profile != "" {
+		if err := applyProfile(opts.profile); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
+	if opts.noColor {
+		red, reset = "", ""
+	}
+	if opts.threads < 1 {
+		opts.threads = 1
+	}
+	if opts.threads > 100 {
+		opts.threads = 100
+	}
+
+	if opts.cache != "" {
+		if err := setupSharedCache(opts.cache, opts.cacheTTL); err != nil {
+			fmt.Fprintf(os.Stderr, "error connecting to -cache: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if opts.onlyKeywords && len(parseKeywords(opts.keywords)) == 0 {
+		fmt.Fprintln(os.Stderr, "-only-keywords needs -keywords")
+		os.Exit(1)
+	}
+	if opts.types != "" {
+		types, err := parseTypes(opts.types)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -types: %v\n", err)
+			os.Exit(1)
+		}
+		allowedTypes = types
+	}
+	if opts.db != "" {
+		if err := checkSQLite(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	if opts.format != "" {
+		if err := parseFormat(opts.format); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -format: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if !opts.silent {
+		printBanner()
+	}
+	if err := loadPopular(opts.popularFile); err != nil {
+		fmt.Fprintf(os.Stderr, "error reading %s: %v\n", opts.popularFile, err)
+	}
+	if opts.auth != "" {
+		if err := loadAuthProfiles(opts.auth); err != nil {
+			fmt.Fprintf(os.Stderr, "error reading %s: %v\n", opts.auth, err)
+			os.Exit(1)
+		}
+	}
+	if opts.hosts != "" {
+		if err := loadHostsFile(opts.hosts); err != nil {
+			fmt.Fprintf(os.Stderr, "error reading %s: %v\n", opts.hosts, err)
+		}
+	}
+
+	if worker || opts.join != "" {
+		if opts.join == "" {
+			fmt.Fprintln(os.Stderr, "worker needs -join")
+			os.Exit(1)
+		}
+		if err := runFleetWorker(opts.join); err != nil {
+			fmt.Fprintf(os.Stderr, "worker: %v\n", err)
+			os.Exit(1)
+		}
 		return
 	}
+	if opts.coordinator != "" {
+		if err := startCoordinator(opts.coordinator, opts.fleetToken); err != nil {
+			fmt.Fprintf(os.Stderr, "coordinator: %v\n", err)
+			os.Exit(1)
+		}
+	}
 
-	filtered := filterManifestURLs(raw)
-	if len(filtered) == 0 {
+	if opts.stdinManifest {
+		run := startRun(1)
+		results, err := scanStdinManifest(opts.manifestType)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		if !opts.silent {
+			defer printCacheStats()
+		}
+		printResults(results)
+		saveResults(results, run)
 		return
 	}
 
-	type inp struct{ u string }
-	type outp struct {
-		u     string
-		vulns []vuln
-		err   error
+	var raw []string
+	if len(opts.list) == 0 && opts.har == "" && opts.burp == "" && opts.gitlab == "" && opts.bitbucket == "" {
+		opts.list = listFlag{"-"}
 	}
-	inputs := make([]inp, 0, len(filtered))
-	for _, u := range filtered {
-		inputs = append(inputs, inp{u: u})
+	// stdin can only be read once, even in daemon mode
+	if slices.Contains(opts.list, "-") {
+		var err error
+		if raw, err = readURLList("-"); err != nil {
+			fmt.Fprintf(os.Stderr, "error reading stdin: %v\n", err)
+		}
 	}
-	worker := func(x inp) (outp, error) {
-		vv, err := checkURLDependencies(x.u, *threads)
-		return outp{u: x.u, vulns: vv, err: err}, nil
+	loadTargets := func() []string {
+		urls := append([]string(nil), raw...)
+		for _, l := range opts.list {
+			if l == "-" {
+				continue
+			}
+			lu, err := readURLList(l)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error reading %s: %v\n", l, err)
+			}
+			urls = append(urls, lu...)
+		}
+		if opts.har != "" {
+			hu, err := readHAR(opts.har)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error reading %s: %v\n", opts.har, err)
+			}
+			urls = append(urls, hu...)
+		}
+		if opts.burp != "" {
+			bu, err := readBurp(opts.burp)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error reading %s: %v\n", opts.burp, err)
+			}
+			urls = append(urls, bu...)
+		}
+		return urls
 	}
-	results, _ := runWorkers(inputs, worker, *threads)
 
-	for _, r := range results {
-		if r.err != nil {
-			continue
+	if opts.daemon {
+		runDaemon(loadTargets)
+		return
+	}
+
+	targets := loadTargets()
+	run := startRun(len(targets))
+	results := scanAll(targets)
+	if fleet != nil {
+		fleet.finish()
+	}
+	if !opts.silent {
+		defer printCacheStats()
+		defer printErrorSummary(results)
+	}
+	if len(results) == 0 {
+		if opts.runReport != "" {
+			saveResults(results, run)
 		}
-		for _, v := range r.vulns {
-			tag := fmt.Sprintf("%s[%s|%d|%s]%s", red, v.Package, v.Status, v.Language, reset)
-			fmt.Printf("%s %s\n", tag, r.u)
+		return
+	}
+	printResults(results)
+	saveResults(results, run)
+}
+
+func saveResults(results []scanResult, run runInfo) {
+	if opts.db != "" {
+		if err := recordFindings(opts.db, results, time.Now()); err != nil {
+			fmt.Fprintf(os.Stderr, "db error: %v\n", err)
+		}
+	}
+	if opts.report != "" {
+		if err := writeReport(opts.report, results, time.Now()); err != nil {
+			fmt.Fprintf(os.Stderr, "report error: %v\n", err)
+		}
+	}
+	if opts.dojoURL != "" {
+		if err := pushDefectDojo(opts.dojoURL, opts.dojoToken, opts.dojoEngagement, opts.dojoProduct, results); err != nil {
+			fmt.Fprintf(os.Stderr, "defectdojo error: %v\n", err)
+		}
+	}
+	if opts.exportURL != "" {
+		if err := pushFindings(opts.exportURL, results); err != nil {
+			fmt.Fprintf(os.Stderr, "export error: %v\n", err)
+		}
+	}
+	if opts.nucleiTemplates != "" {
+		if err := writeNucleiTemplates(opts.nucleiTemplates, results); err != nil {
+			fmt.Fprintf(os.Stderr, "nuclei templates error: %v\n", err)
+		}
+	}
+	if opts.runReport != "" {
+		if err := writeRunReport(opts.runReport, results, run, time.Now()); err != nil {
+			fmt.Fprintf(os.Stderr, "run report error: %v\n", err)
 		}
 	}
 }
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+var deadURLLookups = newLookups[deadURL]("dead url")
+
+type deadURL struct {
+	status int
+	detail string
+}
+
+// checkDeadURL reports URLs whose owner is gone: hosts that no longer resolve
+// (the domain may be registrable) and, when fetch is set, documents that have
+// disappeared, such as files in deleted storage buckets.
+func checkDeadURL(raw string, fetch bool) deadURL {
+	return deadURLLookups.do(raw, func() (deadURL, int) {
+		u, err := url.Parse(raw)
+		if err != nil || u.Host == "" {
+			return deadURL{}, 0
+		}
+		if _, err := resolveHost(context.Background(), u.Hostname()); err != nil {
+			var dnsErr *net.DNSError
+			if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+				return deadURL{detail: fmt.Sprintf("%s does not resolve, the domain may be available to register", u.Hostname())}, 0
+			}
+			return deadURL{}, 0
+		}
+		if !fetch {
+			return deadURL{}, 0
+		}
+		body, status, err := httpGET(raw, map[string]string{"User-Agent": randomUA()})
+		if err != nil {
+			return deadURL{}, 0
+		}
+		switch {
+		case bytes.Contains(body, []byte("NoSuchBucket")):
+			return deadURL{status: status, detail: fmt.Sprintf("%s is in a deleted storage bucket, anyone can recreate it", raw)}, 0
+		case status == http.StatusNotFound || status == http.StatusGone:
+			return deadURL{status: status, detail: fmt.Sprintf("%s returned %d", raw, status)}, 0
+		}
+		return deadURL{status: status}, 0
+	})
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type dnsResult struct {
+	addrs []string
+	err   error
+}
+
+var (
+	dnsLookups  = newLookups[dnsResult]("dns")
+	staticHosts = make(map[string][]string)
+
+	resolverOnce sync.Once
+	resolver     *net.Resolver
+
+	dialer = &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}
+)
+
+func init() {
+	httpClient.Transport.(*http.Transport).DialContext = dialCached
+}
+
+// getResolver returns the resolver set with -resolver, or the system one.
+func getResolver() *net.Resolver {
+	resolverOnce.Do(func() {
+		resolver = net.DefaultResolver
+		if opts.resolver == "" {
+			return
+		}
+		server := opts.resolver
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(server, "53")
+		}
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, server)
+			},
+		}
+	})
+	return resolver
+}
+
+// resolveHost answers from the static hosts mapping, then from the DNS cache.
+// Each host is resolved once per run; failures are not cached.
+func resolveHost(ctx context.Context, host string) ([]string, error) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if addrs, ok := staticHosts[host]; ok {
+		return addrs, nil
+	}
+	r := dnsLookups.do(host, func() (dnsResult, int) {
+		addrs, err := getResolver().LookupHost(ctx, host)
+		return dnsResult{addrs, err}, 16 * len(addrs)
+	})
+	if r.err != nil {
+		dnsLookups.forget(host)
+	}
+	return r.addrs, r.err
+}
+
+func dialCached(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || net.ParseIP(host) != nil {
+		return dialer.DialContext(ctx, network, addr)
+	}
+	addrs, err := resolveHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	errs := make([]error, 0, len(addrs))
+	for _, ip := range addrs {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return nil, &net.DNSError{Err: "no addresses", Name: host, IsNotFound: true}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// loadHostsFile reads a mapping in /etc/hosts format ("ip name [name...]").
+func loadHostsFile(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) < 2 || net.ParseIP(fields[0]) == nil {
+			continue
+		}
+		for _, h := range fields[1:] {
+			h = strings.ToLower(strings.TrimSuffix(h, "."))
+			staticHosts[h] = append(staticHosts[h], fields[0])
+		}
+	}
+	return sc.Err()
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// Brotli has no decoder in the standard library, so br is never advertised.
+// A server that sends it anyway is decoded through the brotli command line
+// tool when it is available.
+const acceptEncoding = "gzip, deflate"
+
+var (
+	brotliOnce sync.Once
+	brotliPath string
+)
+
+func brotliCmd() string {
+	brotliOnce.Do(func() {
+		brotliPath, _ = exec.LookPath("brotli")
+	})
+	return brotliPath
+}
+
+// decodeBody undoes the Content-Encoding codings of body, last applied first.
+func decodeBody(contentEncoding string, body []byte) ([]byte, error) {
+	codings := strings.Split(contentEncoding, ",")
+	for i := len(codings) - 1; i >= 0; i-- {
+		var err error
+		switch c := strings.ToLower(strings.TrimSpace(codings[i])); c {
+		case "", "identity":
+		case "gzip", "x-gzip":
+			body, err = readAllFrom(gzip.NewReader(bytes.NewReader(body)))
+		case "deflate":
+			// RFC 9110 deflate is zlib-wrapped, but raw deflate is common too
+			if d, zerr := readAllFrom(zlib.NewReader(bytes.NewReader(body))); zerr == nil {
+				body = d
+			} else {
+				body, err = io.ReadAll(flate.NewReader(bytes.NewReader(body)))
+			}
+		case "br":
+			body, err = decodeBrotli(body)
+		default:
+			err = fmt.Errorf("unsupported content encoding %q", c)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return body, nil
+}
+
+func readAllFrom(r io.ReadCloser, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func decodeBrotli(body []byte) ([]byte, error) {
+	if brotliCmd() == "" {
+		return nil, fmt.Errorf("brotli response but no brotli command in PATH")
+	}
+	cmd := exec.Command(brotliCmd(), "-d", "-c")
+	cmd.Stdin = bytes.NewReader(body)
+	var out, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("brotli: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out.Bytes(), nil
+}
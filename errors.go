@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// errorCategory classifies why a URL produced no result.
+type errorCategory string
+
+const (
+	errNetwork     errorCategory = "network"
+	errParse       errorCategory = "parse"
+	errSoft404     errorCategory = "soft-404"
+	errNotFound    errorCategory = "not-found"
+	errRateLimited errorCategory = "rate-limited"
+	errPanic       errorCategory = "panic"
+)
+
+type scanError struct {
+	category errorCategory
+	err      error
+}
+
+func (e *scanError) Error() string { return string(e.category) + ": " + e.err.Error() }
+
+func (e *scanError) Unwrap() error { return e.err }
+
+func categorize(c errorCategory, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &scanError{category: c, err: err}
+}
+
+// errorCategoryOf returns the category of a scan error; uncategorized errors
+// come from the transport.
+func errorCategoryOf(err error) errorCategory {
+	var se *scanError
+	if errors.As(err, &se) {
+		return se.category
+	}
+	return errNetwork
+}
+
+// transient reports whether a URL may well answer on the next attempt, so
+// its earlier findings should be kept rather than resolved.
+func transient(err error) bool {
+	switch errorCategoryOf(err) {
+	case errSoft404, errNotFound, errParse:
+		return false
+	}
+	return true
+}
+
+// scanErrorJSON is how a per-URL error appears in JSON output.
+type scanErrorJSON struct {
+	Category errorCategory `json:"category"`
+	Message  string        `json:"message"`
+}
+
+func errorJSON(err error) *scanErrorJSON {
+	if err == nil {
+		return nil
+	}
+	var se *scanError
+	if errors.As(err, &se) {
+		return &scanErrorJSON{Category: se.category, Message: se.err.Error()}
+	}
+	return &scanErrorJSON{Category: errNetwork, Message: err.Error()}
+}
+
+// callWorker recovers a panicking worker as an error for runWorkers.
+func callWorker[T any, R any](worker func(T) (R, error), t T) (val R, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = categorize(errPanic, fmt.Errorf("%v", p))
+		}
+	}()
+	return worker(t)
+}
+
+// safeScan runs one URL's scan, turning a panic in a parser or check into an
+// error on that URL instead of the end of the run.
+func safeScan(u string, scan func() scanResult) (r scanResult) {
+	defer func() {
+		if p := recover(); p != nil {
+			r = scanResult{u: u, err: categorize(errPanic, fmt.Errorf("%v", p))}
+		}
+	}()
+	return scan()
+}
+
+func printErrorSummary(results []scanResult) {
+	counts := make(map[errorCategory]int)
+	for _, r := range results {
+		if r.err != nil {
+			counts[errorCategoryOf(r.err)]++
+		}
+	}
+	cats := make([]string, 0, len(counts))
+	for c := range counts {
+		cats = append(cats, string(c))
+	}
+	sort.Strings(cats)
+	for _, c := range cats {
+		fmt.Fprintf(os.Stderr, "%d URLs failed (%s)\n", counts[errorCategory(c)], c)
+	}
+}
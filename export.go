@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// groupedFinding is one flagged package per registry, with every URL that
+// referenced it as evidence.
+type groupedFinding struct {
+	vuln
+	Registry string   `json:"registry"`
+	URLs     []string `json:"urls"`
+}
+
+func (g groupedFinding) key() string { return g.Registry + ":" + g.Package }
+
+func groupFindings(results []scanResult) []groupedFinding {
+	idx := make(map[string]int)
+	var out []groupedFinding
+	for _, r := range results {
+		if r.err != nil {
+			continue
+		}
+		for _, v := range r.vulns {
+			g := groupedFinding{vuln: v, Registry: registryFor(v.Language)}
+			i, ok := idx[g.key()]
+			if !ok {
+				idx[g.key()] = len(out)
+				g.URLs = []string{r.u}
+				out = append(out, g)
+				continue
+			}
+			e := &out[i]
+			e.URLs = append(e.URLs, r.u)
+			if e.PrivateRegistry == "" {
+				e.PrivateRegistry = v.PrivateRegistry
+			}
+			if confidenceOrder[v.Confidence] < confidenceOrder[e.Confidence] {
+				e.Confidence = v.Confidence
+				e.Snippet = v.Snippet
+			}
+			if v.Type != "" && (e.Type == "" || depTypeOrder[v.Type] < depTypeOrder[e.Type]) {
+				e.Type = v.Type
+			}
+		}
+	}
+	for i := range out {
+		sort.Strings(out[i].URLs)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].key() < out[j].key() })
+	return out
+}
+
+func postJSON(u string, headers map[string]string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned %d: %s", u, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+func pushFindings(exportURL string, results []scanResult) error {
+	return postJSON(exportURL, nil, groupFindings(results))
+}
+
+var dojoSeverity = map[confidence]string{confHigh: "High", confMedium: "Medium", confLow: "Low"}
+
+type dojoFinding struct {
+	Title          string `json:"title"`
+	Description    string `json:"description"`
+	Severity       string `json:"severity"`
+	Mitigation     string `json:"mitigation"`
+	References     string `json:"references"`
+	ComponentName  string `json:"component_name"`
+	FilePath       string `json:"file_path"`
+	UniqueIDTool   string `json:"unique_id_from_tool"`
+	StaticFinding  bool   `json:"static_finding"`
+	DynamicFinding bool   `json:"dynamic_finding"`
+}
+
+func dojoFindings(groups []groupedFinding) []dojoFinding {
+	out := make([]dojoFinding, 0, len(groups))
+	for _, g := range groups {
+		var desc strings.Builder
+		desc.WriteString(g.summary() + "\n\n")
+		desc.WriteString("**Referenced by:**\n\n")
+		for _, u := range g.URLs {
+			fmt.Fprintf(&desc, "- %s\n", u)
+		}
+		if g.Snippet != "" {
+			fmt.Fprintf(&desc, "\n```\n%s\n```\n", g.Snippet)
+		}
+		out = append(out, dojoFinding{
+			Title:          "Dependency confusion: " + g.title(),
+			Description:    desc.String(),
+			Severity:       dojoSeverity[g.severity()],
+			Mitigation:     remediation[g.Language],
+			References:     "https://medium.com/@alex.birsan/dependency-confusion-4a5d60fec610",
+			ComponentName:  g.Package,
+			FilePath:       g.URLs[0],
+			UniqueIDTool:   "dchero:" + g.key(),
+			DynamicFinding: true,
+		})
+	}
+	return out
+}
+
+// scannedAll reports whether results cover a run in which every target was
+// scanned.
+func scannedAll(results []scanResult) bool {
+	for _, r := range results {
+		if r.err != nil {
+			return false
+		}
+	}
+	return len(results) > 0
+}
+
+// pushDefectDojo re-imports the findings into a "DCHero" test of the
+// engagement, so DefectDojo matches them by unique_id_from_tool and updates
+// existing issues instead of creating duplicates. Findings that are no longer
+// reported get closed, but only after a run that scanned every target: a
+// target that failed or an empty run would otherwise close everything it
+// reported before. Without an engagement ID the test goes into a "DCHero"
+// engagement of the named product, both created on the first import.
+func pushDefectDojo(baseURL, token string, engagement int, product string, results []scanResult) error {
+	if token == "" {
+		return errors.New("missing API token (-dojo-token or $DOJO_TOKEN)")
+	}
+	if engagement <= 0 && product == "" {
+		return errors.New("missing engagement ID (-dojo-engagement) or product name (-dojo-product)")
+	}
+	report, err := json.Marshal(map[string]any{"findings": dojoFindings(groupFindings(results))})
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fields := map[string]string{
+		"scan_type":                   "Generic Findings Import",
+		"test_title":                  "DCHero",
+		"active":                      "true",
+		"verified":                    "false",
+		"close_old_findings":          strconv.FormatBool(scannedAll(results)),
+		"deduplication_on_engagement": "true",
+		"minimum_severity":            "Info",
+	}
+	if engagement > 0 {
+		fields["engagement"] = strconv.Itoa(engagement)
+	} else {
+		fields["product_name"] = product
+		fields["engagement_name"] = "DCHero"
+		fields["product_type_name"] = "DCHero"
+		fields["auto_create_context"] = "true"
+	}
+	for k, v := range fields {
+		if err := mw.WriteField(k, v); err != nil {
+			return err
+		}
+	}
+	fw, err := mw.CreateFormFile("file", "dchero.json")
+	if err != nil {
+		return err
+	}
+	if _, err := fw.Write(report); err != nil {
+		return err
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+
+	endpoint := strings.TrimRight(baseURL, "/") + "/api/v2/reimport-scan/"
+	req, err := http.NewRequest(http.MethodPost, endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Authorization", "Token "+token)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("reimport-scan returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+var (
+	vscodeMarketplaceURL = "https://marketplace.visualstudio.com/_apis/public/gallery/extensionquery"
+	vscodeItemURL        = "https://marketplace.visualstudio.com/items?itemName=%s"
+	openVSXURL           = "https://open-vsx.org/api/%s"
+
+	extensionLookups = newLookups[extensionStatus]("vscode")
+)
+
+const (
+	vsFilterTarget        = 8
+	vsFilterExtensionName = 7
+	vsFilterSearchText    = 10
+)
+
+type extensionStatus struct {
+	packageStatus
+	detail string
+}
+
+// vscodeExtensionDeps returns the extensions a VS Code extension manifest
+// installs alongside itself.
+func vscodeExtensionDeps(pj packageJSON) []dependency {
+	// engines is an array in some old manifests
+	var engines map[string]string
+	if json.Unmarshal(pj.Engines, &engines) != nil || engines["vscode"] == "" {
+		return nil
+	}
+	var deps []dependency
+	for _, ids := range [][]string{pj.ExtensionDependencies, pj.ExtensionPack} {
+		for _, id := range ids {
+			deps = append(deps, dependency{Name: strings.ToLower(strings.TrimSpace(id)), Lang: langVSCode})
+		}
+	}
+	return deps
+}
+
+func isBrowserExtensionManifest(p string) bool {
+	return strings.EqualFold(path.Base(p), "manifest.json")
+}
+
+// parseBrowserExtensionManifest returns the self-hosted update URLs of a
+// Chrome or Firefox extension manifest. Web app manifests share the file name
+// and are recognized by the missing manifest_version.
+func parseBrowserExtensionManifest(body []byte) ([]dependency, error) {
+	var m struct {
+		ManifestVersion int    `json:"manifest_version"`
+		UpdateURL       string `json:"update_url"`
+		Browser         struct {
+			Gecko struct {
+				UpdateURL string `json:"update_url"`
+			} `json:"gecko"`
+		} `json:"browser_specific_settings"`
+		Applications struct {
+			Gecko struct {
+				UpdateURL string `json:"update_url"`
+			} `json:"gecko"`
+		} `json:"applications"`
+	}
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, err
+	}
+	if m.ManifestVersion == 0 {
+		return nil, nil
+	}
+	var deps []dependency
+	for _, u := range []string{m.UpdateURL, m.Browser.Gecko.UpdateURL, m.Applications.Gecko.UpdateURL} {
+		if strings.HasPrefix(u, "http") && privateRegistryHost(u) != "" && !isStoreUpdateURL(u) {
+			deps = append(deps, dependency{Name: u, Source: u, Lang: langBrowserExt})
+		}
+	}
+	return deps, nil
+}
+
+func isStoreUpdateURL(u string) bool {
+	p, err := url.Parse(u)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(p.Hostname()) {
+	case "clients2.google.com", "update.googleapis.com", "edge.microsoft.com", "versioncheck.addons.mozilla.org":
+		return true
+	}
+	return false
+}
+
+// vscodeQuery asks the Visual Studio Marketplace gallery API for extensions
+// matching one criterion and returns the publisher.name IDs found.
+func vscodeQuery(filterType int, value string) ([]string, int, error) {
+	q := map[string]any{
+		"filters": []map[string]any{{
+			"criteria": []map[string]any{
+				{"filterType": vsFilterTarget, "value": "Microsoft.VisualStudio.Code"},
+				{"filterType": filterType, "value": value},
+			},
+			"pageSize": 10,
+		}},
+		"flags": 0,
+	}
+	b, err := json.Marshal(q)
+	if err != nil {
+		return nil, 0, err
+	}
+	req, err := http.NewRequest(http.MethodPost, vscodeMarketplaceURL, bytes.NewReader(b))
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json;api-version=3.0-preview.1")
+	req.Header.Set("User-Agent", randomUA())
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp.StatusCode, nil
+	}
+	var res struct {
+		Results []struct {
+			Extensions []struct {
+				Name      string `json:"extensionName"`
+				Publisher struct {
+					Name string `json:"publisherName"`
+				} `json:"publisher"`
+			} `json:"extensions"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 8<<20)).Decode(&res); err != nil {
+		return nil, resp.StatusCode, err
+	}
+	var ids []string
+	for _, r := range res.Results {
+		for _, e := range r.Extensions {
+			ids = append(ids, strings.ToLower(e.Publisher.Name+"."+e.Name))
+		}
+	}
+	return ids, resp.StatusCode, nil
+}
+
+func openVSXExists(p string) bool {
+	_, status, err := httpGET(fmt.Sprintf(openVSXURL, p), map[string]string{"User-Agent": randomUA()})
+	return err != nil || status != http.StatusNotFound
+}
+
+// checkExtension reports a VS Code extension ID that nobody has published.
+// A missing extension only matters while its publisher ID is free: on the
+// Visual Studio Marketplace publishers are owned, on Open VSX namespaces are
+// claimed by whoever publishes first.
+func checkExtension(id string) (findingKind, int, string) {
+	publisher, name, ok := strings.Cut(id, ".")
+	if !ok || publisher == "" || name == "" {
+		return "", 0, ""
+	}
+	r := extensionLookups.do(id, func() (extensionStatus, int) {
+		ids, status, err := vscodeQuery(vsFilterExtensionName, id)
+		if err != nil || status != http.StatusOK {
+			return extensionStatus{packageStatus: packageStatus{status: status}}, 0
+		}
+		for _, x := range ids {
+			if x == id {
+				return extensionStatus{packageStatus: packageStatus{status: http.StatusOK}}, 0
+			}
+		}
+		var free []string
+		if ids, status, err := vscodeQuery(vsFilterSearchText, fmt.Sprintf("publisher:%q", publisher)); err == nil && status == http.StatusOK {
+			taken := false
+			for _, x := range ids {
+				taken = taken || strings.HasPrefix(x, publisher+".")
+			}
+			if !taken {
+				free = append(free, "Visual Studio Marketplace")
+			}
+		}
+		if !openVSXExists(url.PathEscape(publisher)) {
+			free = append(free, "Open VSX")
+		}
+		if len(free) == 0 {
+			return extensionStatus{packageStatus: packageStatus{status: http.StatusNotFound}}, 0
+		}
+		return extensionStatus{packageStatus: packageStatus{unclaimed: true, status: http.StatusNotFound},
+			detail: fmt.Sprintf("publisher %s has no extensions on %s", publisher, strings.Join(free, " or "))}, len(id)
+	})
+	if r.unclaimed {
+		return kindUnclaimed, r.status, r.detail
+	}
+	return "", r.status, ""
+}
+
+// checkUpdateURL reports a browser extension update URL whose host or
+// storage bucket is gone, letting a new owner push updates to every install.
+func checkUpdateURL(u string) (findingKind, int, string) {
+	if dead := checkDeadURL(u, true); dead.detail != "" {
+		return kindDeadRepo, dead.status, dead.detail
+	}
+	return "", 0, ""
+}
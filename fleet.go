@@ -0,0 +1,427 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// how long a worker may hold a batch before it is handed to someone else
+	fleetLeaseTimeout = 15 * time.Minute
+	// how often idle workers ask for work
+	fleetPoll = 2 * time.Second
+
+	// fleetClient verifies the coordinator's certificate: unlike targets,
+	// it is handed the token and every finding.
+	fleetClient = &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: &http.Transport{Proxy: http.ProxyFromEnvironment},
+	}
+)
+
+var errFleetAuth = errors.New("coordinator rejected -fleet-token")
+
+type fleetBatch struct {
+	ID    int      `json:"id"`
+	Round int      `json:"round"`
+	URLs  []string `json:"urls"`
+}
+
+type fleetLeaseRequest struct {
+	Worker string `json:"worker"`
+}
+
+type fleetLeaseResponse struct {
+	Batch *fleetBatch `json:"batch,omitempty"`
+	Done  bool        `json:"done,omitempty"`
+}
+
+type fleetResults struct {
+	Worker  string      `json:"worker"`
+	ID      int         `json:"id"`
+	Results []urlResult `json:"results"`
+}
+
+type fleetLease struct {
+	batch   fleetBatch
+	worker  string
+	expires time.Time
+}
+
+// coordinator hands batches of URLs to workers (dchero worker -join) and
+// collects what they find. Leases that are not answered in time go back in
+// the queue, so a worker that dies only delays its batch.
+type coordinator struct {
+	token string
+
+	mu       sync.Mutex
+	round    int
+	nextID   int
+	pending  []fleetBatch
+	leased   map[int]fleetLease
+	results  []scanResult
+	left     int
+	roundEnd chan struct{}
+	finished bool
+	workers  map[string]struct{}
+	packages map[string]int
+}
+
+var fleet *coordinator
+
+// startCoordinator serves batches on addr. Anyone who reaches it could read
+// the targets and inject findings, so a coordinator that is not bound to
+// loopback always requires a token and makes one up when none is given.
+func startCoordinator(addr, token string) error {
+	if token == "" && !loopbackAddr(addr) {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return err
+		}
+		token = hex.EncodeToString(b)
+		fmt.Fprintf(os.Stderr, "coordinator: no -fleet-token given, workers must join with -fleet-token %s\n", token)
+	}
+	c := &coordinator{
+		token:    token,
+		leased:   make(map[int]fleetLease),
+		workers:  make(map[string]struct{}),
+		packages: make(map[string]int),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/lease", c.auth(c.serveLease))
+	mux.HandleFunc("/results", c.auth(c.serveResults))
+	mux.HandleFunc("/package", c.auth(c.servePackage))
+	srv := &http.Server{Addr: addr, Handler: mux}
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+	// surface a bad address before the scan starts
+	select {
+	case err := <-errc:
+		return err
+	case <-time.After(200 * time.Millisecond):
+	}
+	fleet = c
+	return nil
+}
+
+func loopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (c *coordinator) auth(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if c.token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+c.token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h(w, r)
+	}
+}
+
+// scan shards urls into batches and blocks until workers have answered
+// every one of them.
+func (c *coordinator) scan(urls []string) []scanResult {
+	size := opts.shardSize
+	if size < 1 {
+		size = 1
+	}
+	seen := make(map[string]struct{})
+	var uniq []string
+	for _, u := range urls {
+		u = strings.TrimSpace(u)
+		if _, ok := seen[u]; ok || u == "" {
+			continue
+		}
+		seen[u] = struct{}{}
+		uniq = append(uniq, u)
+	}
+
+	c.mu.Lock()
+	c.round++
+	c.pending, c.results = nil, nil
+	c.leased = make(map[int]fleetLease)
+	c.packages = make(map[string]int)
+	for i := 0; i < len(uniq); i += size {
+		c.nextID++
+		c.pending = append(c.pending, fleetBatch{ID: c.nextID, Round: c.round, URLs: uniq[i:min(i+size, len(uniq))]})
+	}
+	c.left = len(c.pending)
+	end := make(chan struct{})
+	c.roundEnd = end
+	if c.left == 0 {
+		close(end)
+	}
+	c.mu.Unlock()
+
+	if !opts.silent {
+		fmt.Fprintf(os.Stderr, "coordinator: %d URLs in %d batches, waiting for workers\n", len(uniq), c.left)
+	}
+	<-end
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.results
+}
+
+// finish tells polling workers there is nothing left and gives them a
+// moment to hear it before the process exits.
+func (c *coordinator) finish() {
+	c.mu.Lock()
+	c.finished = true
+	c.mu.Unlock()
+	time.Sleep(2 * fleetPoll)
+}
+
+func (c *coordinator) serveLease(w http.ResponseWriter, r *http.Request) {
+	var req fleetLeaseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	if _, ok := c.workers[req.Worker]; !ok {
+		c.workers[req.Worker] = struct{}{}
+		if !opts.silent {
+			fmt.Fprintf(os.Stderr, "coordinator: worker %s joined\n", req.Worker)
+		}
+	}
+	now := time.Now()
+	for id, l := range c.leased {
+		if now.After(l.expires) {
+			delete(c.leased, id)
+			c.pending = append(c.pending, l.batch)
+			if !opts.silent {
+				fmt.Fprintf(os.Stderr, "coordinator: batch %d from %s timed out, requeued\n", id, l.worker)
+			}
+		}
+	}
+	var resp fleetLeaseResponse
+	switch {
+	case len(c.pending) > 0:
+		b := c.pending[0]
+		c.pending = c.pending[1:]
+		c.leased[b.ID] = fleetLease{batch: b, worker: req.Worker, expires: now.Add(fleetLeaseTimeout)}
+		resp.Batch = &b
+	case c.finished:
+		resp.Done = true
+	}
+	c.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (c *coordinator) serveResults(w http.ResponseWriter, r *http.Request) {
+	var req fleetResults
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// answers for requeued batches count once, whoever is first
+	if _, ok := c.leased[req.ID]; ok {
+		delete(c.leased, req.ID)
+	} else if i := c.pendingIndex(req.ID); i >= 0 {
+		c.pending = append(c.pending[:i], c.pending[i+1:]...)
+	} else {
+		return
+	}
+	for _, ur := range req.Results {
+		c.results = append(c.results, ur.scanResult())
+	}
+	c.left--
+	if c.left == 0 {
+		close(c.roundEnd)
+	}
+}
+
+func (c *coordinator) pendingIndex(id int) int {
+	for i, b := range c.pending {
+		if b.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// servePackage is the fleet-wide package cache for workers without -cache.
+func (c *coordinator) servePackage(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch r.Method {
+	case http.MethodGet:
+		status, ok := c.packages[key]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, status)
+	case http.MethodPut:
+		b, _ := io.ReadAll(io.LimitReader(r.Body, 16))
+		status, err := strconv.Atoi(string(b))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		c.packages[key] = status
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (ur urlResult) scanResult() scanResult {
+	r := scanResult{u: ur.URL, vulns: ur.Findings}
+	if ur.Error != nil {
+		r.err = categorize(ur.Error.Category, errors.New(ur.Error.Message))
+	}
+	return r
+}
+
+// fleetRequest sends one request to the coordinator and decodes its answer
+// into out, if any.
+func fleetRequest(method, u string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if opts.fleetToken != "" {
+		req.Header.Set("Authorization", "Bearer "+opts.fleetToken)
+	}
+	resp, err := fleetClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		return errFleetAuth
+	}
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned %d: %s", u, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// fleetPackageStore shares package answers through the coordinator.
+type fleetPackageStore struct {
+	base string
+}
+
+func (s fleetPackageStore) url(key string) string {
+	return s.base + "/package?key=" + url.QueryEscape(key)
+}
+
+func (s fleetPackageStore) load(key string) (packageStatus, bool) {
+	var status int
+	if err := fleetRequest(http.MethodGet, s.url(key), nil, &status); err != nil {
+		return packageStatus{}, false
+	}
+	return sharedPackageStatus(status), true
+}
+
+func (s fleetPackageStore) store(key string, v packageStatus) {
+	if !shareable(v) {
+		return
+	}
+	fleetRequest(http.MethodPut, s.url(key), v.status, nil)
+}
+
+// runFleetWorker scans batches leased from the coordinator at base until it
+// says the scan is over.
+func runFleetWorker(base string) error {
+	base = strings.TrimSuffix(base, "/")
+	if !strings.Contains(base, "://") {
+		base = "http://" + base
+	}
+	if u, err := url.Parse(base); err == nil && u.Scheme == "http" && !loopbackAddr(u.Host) && !opts.silent {
+		fmt.Fprintln(os.Stderr, "worker: the coordinator is plain HTTP, so the token, targets and findings travel unencrypted; put it behind TLS and join with https://")
+	}
+	host, _ := os.Hostname()
+	id := fmt.Sprintf("%s-%d", host, os.Getpid())
+	if opts.cache == "" {
+		packageLookups.shared = fleetPackageStore{base: base}
+	}
+
+	round, fails := 0, 0
+	for {
+		var lr fleetLeaseResponse
+		if err := fleetRequest(http.MethodPost, base+"/lease", fleetLeaseRequest{Worker: id}, &lr); err != nil {
+			if errors.Is(err, errFleetAuth) {
+				return err
+			}
+			fails++
+			if fails >= 30 {
+				return fmt.Errorf("coordinator unreachable: %v", err)
+			}
+			time.Sleep(fleetPoll)
+			continue
+		}
+		fails = 0
+		if lr.Done {
+			return nil
+		}
+		if lr.Batch == nil {
+			time.Sleep(fleetPoll)
+			continue
+		}
+		// a new daemon round wants fresh registry answers
+		if lr.Batch.Round != round {
+			resetCaches()
+			round = lr.Batch.Round
+		}
+
+		results := scanURLs(lr.Batch.URLs, opts.threads)
+		out := fleetResults{Worker: id, ID: lr.Batch.ID, Results: make([]urlResult, 0, len(results))}
+		for _, r := range results {
+			out.Results = append(out.Results, urlResult{URL: r.u, Findings: r.vulns, Error: errorJSON(r.err)})
+		}
+		var err error
+		for attempt := 0; attempt < 5; attempt++ {
+			if err = fleetRequest(http.MethodPost, base+"/results", out, nil); err == nil {
+				break
+			}
+			time.Sleep(fleetPoll)
+		}
+		if err != nil {
+			// the lease expires and another worker picks the batch up
+			fmt.Fprintf(os.Stderr, "worker: sending batch %d: %v\n", lr.Batch.ID, err)
+		} else if !opts.silent {
+			fmt.Fprintf(os.Stderr, "worker: batch %d done (%d URLs)\n", lr.Batch.ID, len(lr.Batch.URLs))
+		}
+	}
+}
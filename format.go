@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// outputFormat is the -format template for finding lines, nil for the
+// default bracketed output.
+var outputFormat *template.Template
+
+// formatData is what a -format template sees: every vuln field plus the
+// URLs the finding was seen on (URL is the first).
+type formatData struct {
+	vuln
+	URL      string
+	URLs     []string
+	Registry string
+	Title    string
+}
+
+func parseFormat(s string) error {
+	if !strings.HasSuffix(s, "\n") {
+		s += "\n"
+	}
+	t, err := template.New("format").Funcs(template.FuncMap{"join": strings.Join}).Parse(s)
+	if err != nil {
+		return err
+	}
+	// catch unknown fields before the scan rather than on the first finding
+	if err := t.Execute(io.Discard, formatData{}); err != nil {
+		return err
+	}
+	outputFormat = t
+	return nil
+}
+
+func printFormatted(v vuln, urls []string) {
+	d := formatData{vuln: v, URLs: urls, Registry: registryFor(v.Language), Title: v.title()}
+	if len(urls) > 0 {
+		d.URL = urls[0]
+	}
+	if err := outputFormat.Execute(os.Stdout, d); err != nil {
+		fmt.Fprintf(os.Stderr, "format error: %v\n", err)
+	}
+}
+
+// urlResult is one line of -json output: everything a URL produced,
+// including why it produced nothing.
+type urlResult struct {
+	URL      string         `json:"url"`
+	Findings []vuln         `json:"findings,omitempty"`
+	Error    *scanErrorJSON `json:"error,omitempty"`
+}
+
+func printJSON(results []scanResult) {
+	enc := json.NewEncoder(os.Stdout)
+	for _, r := range results {
+		if len(r.vulns) == 0 && r.err == nil {
+			continue
+		}
+		enc.Encode(urlResult{URL: r.u, Findings: r.vulns, Error: errorJSON(r.err)})
+	}
+}
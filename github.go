@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GitHub allows 10 code searches a minute, so they are spaced out.
+const githubSearchInterval = 6500 * time.Millisecond
+
+var (
+	githubAPI     = "https://api.github.com"
+	githubMu      sync.Mutex
+	githubNext    time.Time
+	githubLookups = newLookups[githubRefs]("github")
+)
+
+// githubRefs are the manifests on GitHub that reference a package, with the
+// owners that are not the target's.
+type githubRefs struct {
+	total  int
+	owners []string
+	err    error
+}
+
+// external owners above which an unclaimed name is probably a public one
+// that was never published, rather than an internal package
+const githubWidelyUsed = 3
+
+func githubSearchCode(q string) ([]byte, int, error) {
+	for attempt := 0; ; attempt++ {
+		githubMu.Lock()
+		wait := time.Until(githubNext)
+		githubNext = time.Now().Add(max(wait, 0) + githubSearchInterval)
+		githubMu.Unlock()
+		time.Sleep(wait)
+
+		h := map[string]string{"Accept": "application/vnd.github+json", "Authorization": "Bearer " + opts.githubToken, "User-Agent": "dchero"}
+		body, status, header, err := httpGETHeader(githubAPI+"/search/code?per_page=100&q="+url.QueryEscape(q), h)
+		if err != nil {
+			return nil, 0, err
+		}
+		if (status == http.StatusForbidden || status == http.StatusTooManyRequests) && attempt < 2 {
+			time.Sleep(githubRetryAfter(header))
+			continue
+		}
+		return body, status, nil
+	}
+}
+
+func githubRetryAfter(h http.Header) time.Duration {
+	if s, err := strconv.Atoi(h.Get("Retry-After")); err == nil {
+		return time.Duration(s) * time.Second
+	}
+	if reset, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		if d := time.Until(time.Unix(reset, 0)); d > 0 {
+			return d + time.Second
+		}
+	}
+	return time.Minute
+}
+
+func githubReferences(pkg string, lang language) githubRefs {
+	return githubLookups.do(lookupKey(pkg, lang), func() (githubRefs, int) {
+		file := "package.json"
+		switch lang {
+		case langPython:
+			file = "requirements.txt"
+		case langCocoaPods:
+			file = "Podfile"
+		case langSwift:
+			file = "Package.swift"
+		case langHelm:
+			file = "Chart.yaml"
+		case langBrowserExt:
+			file = "manifest.json"
+		case langR:
+			file = "DESCRIPTION"
+		}
+		body, status, err := githubSearchCode(fmt.Sprintf("%q filename:%s", pkg, file))
+		if err != nil {
+			return githubRefs{err: err}, 0
+		}
+		if status != http.StatusOK {
+			return githubRefs{err: fmt.Errorf("github code search returned %d", status)}, 0
+		}
+		var res struct {
+			TotalCount int `json:"total_count"`
+			Items      []struct {
+				Repository struct {
+					Owner struct {
+						Login string `json:"login"`
+					} `json:"owner"`
+				} `json:"repository"`
+			} `json:"items"`
+		}
+		if err := json.Unmarshal(body, &res); err != nil {
+			return githubRefs{err: err}, 0
+		}
+		excluded := make(map[string]struct{})
+		for _, o := range splitList(opts.githubOwners) {
+			excluded[strings.ToLower(o)] = struct{}{}
+		}
+		owners := make(map[string]struct{})
+		for _, it := range res.Items {
+			o := it.Repository.Owner.Login
+			if _, ok := excluded[strings.ToLower(o)]; !ok && o != "" {
+				owners[o] = struct{}{}
+			}
+		}
+		r := githubRefs{total: res.TotalCount}
+		for o := range owners {
+			r.owners = append(r.owners, o)
+		}
+		sort.Strings(r.owners)
+		return r, 64 * len(r.owners)
+	})
+}
+
+// corroborateGitHub rates unclaimed findings by how widely their name is used
+// on GitHub outside the target: names nobody else references are internal
+// (high confidence), names many unrelated projects reference were most likely
+// never meant to be published (low confidence).
+func corroborateGitHub(results []scanResult) {
+	if opts.githubToken == "" {
+		fmt.Fprintln(os.Stderr, "github: code search needs a token (-github-token or $GITHUB_TOKEN)")
+		return
+	}
+	for i := range results {
+		for j := range results[i].vulns {
+			v := &results[i].vulns[j]
+			if v.Kind != kindUnclaimed {
+				continue
+			}
+			refs := githubReferences(v.Package, v.Language)
+			if refs.err != nil {
+				fmt.Fprintf(os.Stderr, "github %s: %v\n", v.Package, refs.err)
+				continue
+			}
+			switch n := len(refs.owners); {
+			case n == 0:
+				v.Confidence = confHigh
+				v.Corroboration = "No repository outside the target references it on GitHub, so it is most likely an internal name."
+			case n >= githubWidelyUsed:
+				v.Confidence = confLow
+				v.Corroboration = fmt.Sprintf("%d other GitHub owners reference it (%s), so it is likely a public name that was never published rather than an internal one.",
+					n, strings.Join(refs.owners[:min(n, 5)], ", "))
+			default:
+				v.Corroboration = fmt.Sprintf("It is also referenced on GitHub by %s.", strings.Join(refs.owners, ", "))
+			}
+		}
+	}
+}
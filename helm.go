@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+var (
+	artifactHubURL = "https://artifacthub.io/api/v1/packages/search?kind=0&limit=60&ts_query_web=%s"
+
+	// chart repositories that are public themselves, so a chart missing from
+	// Artifact Hub is not a fallback risk
+	publicChartHosts = map[string]struct{}{
+		"charts.bitnami.com":                                 {},
+		"charts.helm.sh":                                     {},
+		"kubernetes-charts.storage.googleapis.com":           {},
+		"kubernetes-charts-incubator.storage.googleapis.com": {},
+		"registry-1.docker.io":                               {},
+		"ghcr.io":                                            {},
+	}
+)
+
+func isHelmManifest(p string) bool {
+	switch strings.ToLower(path.Base(p)) {
+	case "chart.yaml", "requirements.yaml":
+		return true
+	}
+	return false
+}
+
+func yamlScalar(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	return strings.Trim(s, `"'`)
+}
+
+// parseChartDependencies reads the dependencies list of Chart.yaml (v2) or
+// requirements.yaml (v1).
+func parseChartDependencies(body []byte) []dependency {
+	var deps []dependency
+	in := false
+	cur := -1
+	sc := lineScanner(body)
+	for sc.Scan() {
+		line := sc.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(trimmed, "-") {
+			in = strings.HasPrefix(trimmed, "dependencies:")
+			cur = -1
+			continue
+		}
+		if !in {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+			deps = append(deps, dependency{})
+			cur = len(deps) - 1
+			trimmed = strings.TrimSpace(strings.TrimPrefix(trimmed, "-"))
+		}
+		if cur < 0 {
+			continue
+		}
+		key, val, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "name":
+			deps[cur].Name = yamlScalar(val)
+		case "version":
+			deps[cur].Spec = yamlScalar(val)
+		case "repository":
+			deps[cur].Source = yamlScalar(val)
+			deps[cur].PrivateRegistry = privateChartHost(deps[cur].Source)
+		}
+	}
+	return deps
+}
+
+func privateChartHost(repo string) string {
+	if !strings.HasPrefix(repo, "http") && !strings.HasPrefix(repo, "oci://") {
+		return ""
+	}
+	h := privateRegistryHost(strings.Replace(repo, "oci://", "https://", 1))
+	if _, ok := publicChartHosts[h]; ok {
+		return ""
+	}
+	return h
+}
+
+// onArtifactHub reports whether a chart with exactly this name is published
+// in any repository listed on Artifact Hub.
+func onArtifactHub(name string) (bool, int) {
+	r := packageLookups.do("artifacthub:"+name, func() (packageStatus, int) {
+		body, status, err := httpGET(fmt.Sprintf(artifactHubURL, url.QueryEscape(name)), map[string]string{"User-Agent": randomUA(), "Accept": "application/json"})
+		if err != nil || status != http.StatusOK {
+			return packageStatus{status: status}, 0
+		}
+		var res struct {
+			Packages []struct {
+				Name string `json:"name"`
+			} `json:"packages"`
+		}
+		if json.Unmarshal(body, &res) != nil {
+			return packageStatus{status: status}, 0
+		}
+		for _, p := range res.Packages {
+			if strings.EqualFold(p.Name, name) {
+				return packageStatus{status: http.StatusOK}, 0
+			}
+		}
+		return packageStatus{unclaimed: true, status: http.StatusNotFound}, 0
+	})
+	if !shareable(r) {
+		packageLookups.forget("artifacthub:" + name)
+	}
+	return !r.unclaimed, r.status
+}
+
+// checkChart checks one chart dependency: a dead repository first, then,
+// for charts served from an alias or a private repository, whether the name
+// is free on the public chart ecosystem.
+func checkChart(name, repo string) (findingKind, int, string) {
+	if repo == "" || strings.HasPrefix(repo, "file://") {
+		return "", 0, ""
+	}
+	if strings.Contains(repo, "://") {
+		index, fetch := strings.TrimRight(repo, "/")+"/index.yaml", true
+		if strings.HasPrefix(repo, "oci://") {
+			index, fetch = strings.Replace(repo, "oci://", "https://", 1), false
+		}
+		if dead := checkDeadURL(index, fetch); dead.detail != "" {
+			return kindDeadRepo, dead.status, dead.detail
+		}
+	}
+	private := strings.HasPrefix(repo, "@") || strings.HasPrefix(repo, "alias:") || privateChartHost(repo) != ""
+	if !private {
+		return "", 0, ""
+	}
+	if found, status := onArtifactHub(name); !found && status == http.StatusNotFound {
+		return kindUnclaimed, status, ""
+	}
+	return "", 0, ""
+}
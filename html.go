@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	scriptTagRe = regexp.MustCompile(`(?is)<script\b([^>]*)>(.*?)</script\s*>`)
+	srcAttrRe   = regexp.MustCompile(`(?i)\bsrc\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+	typeAttrRe  = regexp.MustCompile(`(?i)\btype\s*=\s*["']?([^"'\s>]+)`)
+)
+
+func isHTML(contentType string, body []byte) bool {
+	if strings.Contains(strings.ToLower(contentType), "text/html") {
+		return true
+	}
+	head := bytes.ToLower(bytes.TrimSpace(body[:min(len(body), 512)]))
+	return bytes.HasPrefix(head, []byte("<!doctype html")) || bytes.HasPrefix(head, []byte("<html"))
+}
+
+func looksLikePage(p string) bool {
+	if p == "" || strings.HasSuffix(p, "/") {
+		return true
+	}
+	switch strings.ToLower(path.Ext(p)) {
+	case "", ".html", ".htm", ".php", ".asp", ".aspx", ".jsp":
+		return true
+	}
+	return false
+}
+
+// parseHTML returns the packages imported by inline scripts and the absolute
+// URLs of external scripts.
+func parseHTML(pageURL string, body []byte) ([]dependency, []string) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, nil
+	}
+	var inline strings.Builder
+	var links []string
+	for _, m := range scriptTagRe.FindAllSubmatch(body, -1) {
+		attrs := m[1]
+		if t := typeAttrRe.FindSubmatch(attrs); t != nil {
+			typ := strings.ToLower(string(t[1]))
+			if typ != "module" && !strings.Contains(typ, "javascript") {
+				continue
+			}
+		}
+		if s := srcAttrRe.FindSubmatch(attrs); s != nil {
+			src := string(bytes.Join(s[1:], nil))
+			ref, err := url.Parse(strings.TrimSpace(src))
+			if err != nil || src == "" {
+				continue
+			}
+			abs := base.ResolveReference(ref)
+			abs.Fragment = ""
+			links = append(links, abs.String())
+			continue
+		}
+		inline.Write(m[2])
+		inline.WriteByte('\n')
+	}
+	return namesToDeps(extractPackagesFromJS(inline.String())), links
+}
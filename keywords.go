@@ -0,0 +1,57 @@
+package main
+
+import "strings"
+
+// normalizeKeyword folds case and separators so "acme" matches
+// "@ACME/utils", "acme_corp" and "acmecorp" alike.
+func normalizeKeyword(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		}
+		return -1
+	}, s)
+}
+
+func parseKeywords(s string) []string {
+	var out []string
+	for _, k := range strings.Split(s, ",") {
+		if k = normalizeKeyword(k); k != "" {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+func matchKeyword(pkg string, keywords []string) string {
+	n := normalizeKeyword(pkg)
+	for _, k := range keywords {
+		if strings.Contains(n, k) {
+			return k
+		}
+	}
+	return ""
+}
+
+// applyKeywords marks findings whose package name contains a target brand
+// keyword and, with -only-keywords, drops the rest.
+func applyKeywords(results []scanResult) {
+	keywords := parseKeywords(opts.keywords)
+	if len(keywords) == 0 {
+		return
+	}
+	for i := range results {
+		vulns := results[i].vulns[:0]
+		for _, v := range results[i].vulns {
+			v.Keyword = matchKeyword(v.Package, keywords)
+			if v.Keyword == "" && opts.onlyKeywords {
+				continue
+			}
+			vulns = append(vulns, v)
+		}
+		results[i].vulns = vulns
+	}
+}
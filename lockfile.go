@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	publicRegistryHosts = map[string]struct{}{
+		"registry.npmjs.org":         {},
+		"registry.yarnpkg.com":       {},
+		"registry.npmmirror.com":     {},
+		"registry.npm.taobao.org":    {},
+		"registry.bower.io":          {},
+		"trunk.cocoapods.org":        {},
+		"cdn.cocoapods.org":          {},
+		"artifacthub.io":             {},
+		"cran.r-project.org":         {},
+		"cloud.r-project.org":        {},
+		"cran.rstudio.com":           {},
+		"packagemanager.posit.co":    {},
+		"packagemanager.rstudio.com": {},
+		"bioconductor.org":           {},
+		"pypi.org":                   {},
+		"pypi.python.org":            {},
+		"files.pythonhosted.org":     {},
+	}
+	// hosts that serve git or tarball dependencies rather than a registry
+	sourceHosts = map[string]struct{}{
+		"github.com":          {},
+		"codeload.github.com": {},
+		"gitlab.com":          {},
+		"bitbucket.org":       {},
+	}
+
+	pnpmTarballRe = regexp.MustCompile(`tarball:\s*['"]?([^,'"}\s]+)`)
+)
+
+// privateRegistryHost returns the host of a resolved/index URL when it is
+// neither a public registry nor a source forge.
+func privateRegistryHost(raw string) string {
+	raw = strings.TrimPrefix(strings.TrimSpace(raw), "git+")
+	p, err := url.Parse(raw)
+	if err != nil || p.Host == "" {
+		return ""
+	}
+	host := strings.ToLower(p.Hostname())
+	if _, ok := publicRegistryHosts[host]; ok {
+		return ""
+	}
+	if _, ok := sourceHosts[host]; ok {
+		return ""
+	}
+	return host
+}
+
+func isLockfile(targetURL string) bool {
+	switch strings.ToLower(path.Base(targetURL)) {
+	case "package-lock.json", "npm-shrinkwrap.json", "yarn.lock", "pnpm-lock.yaml", "pipfile.lock":
+		return true
+	}
+	return false
+}
+
+func parseLockfile(targetURL string, body []byte) ([]dependency, language, error) {
+	switch strings.ToLower(path.Base(targetURL)) {
+	case "package-lock.json", "npm-shrinkwrap.json":
+		deps, err := parseNPMLock(body)
+		return deps, langJS, err
+	case "yarn.lock":
+		return parseYarnLock(body), langJS, nil
+	case "pnpm-lock.yaml":
+		return parsePNPMLock(body), langJS, nil
+	default:
+		deps, err := parsePipfileLock(body)
+		return deps, langPython, err
+	}
+}
+
+type npmLockEntry struct {
+	Version     string `json:"version"`
+	Resolved    string `json:"resolved"`
+	Dev         bool   `json:"dev"`
+	DevOptional bool   `json:"devOptional"`
+}
+
+// npmLockV1Entry nests the packages installed below it; in the packages map
+// of v2/3 dependencies are name-to-range strings instead.
+type npmLockV1Entry struct {
+	npmLockEntry
+	Dependencies map[string]npmLockV1Entry `json:"dependencies"`
+}
+
+func (e npmLockEntry) depType() depType {
+	if e.Dev || e.DevOptional {
+		return depDev
+	}
+	return ""
+}
+
+func parseNPMLock(body []byte) ([]dependency, error) {
+	var lock struct {
+		Packages     map[string]npmLockEntry   `json:"packages"`
+		Dependencies map[string]npmLockV1Entry `json:"dependencies"`
+	}
+	if err := json.Unmarshal(body, &lock); err != nil {
+		return nil, err
+	}
+	var deps []dependency
+	// lockfileVersion 2/3
+	for key, e := range lock.Packages {
+		i := strings.LastIndex(key, "node_modules/")
+		if i < 0 {
+			continue
+		}
+		deps = append(deps, dependency{Name: key[i+len("node_modules/"):], Spec: e.Version, PrivateRegistry: privateRegistryHost(e.Resolved), Type: e.depType()})
+	}
+	if len(deps) > 0 {
+		return deps, nil
+	}
+	// lockfileVersion 1
+	var walk func(map[string]npmLockV1Entry)
+	walk = func(m map[string]npmLockV1Entry) {
+		for name, e := range m {
+			deps = append(deps, dependency{Name: name, Spec: e.Version, PrivateRegistry: privateRegistryHost(e.Resolved), Type: e.depType()})
+			walk(e.Dependencies)
+		}
+	}
+	walk(lock.Dependencies)
+	return deps, nil
+}
+
+// splitNameSpec splits "name@range" and "@scope/name@range".
+func splitNameSpec(s string) (string, string) {
+	s = strings.Trim(strings.TrimSpace(s), `"'`)
+	i := strings.LastIndex(s, "@")
+	if i <= 0 {
+		return s, ""
+	}
+	return s[:i], s[i+1:]
+}
+
+func parseYarnLock(body []byte) []dependency {
+	var deps []dependency
+	cur := -1
+	sc := lineScanner(body)
+	for sc.Scan() {
+		line := sc.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if !strings.HasPrefix(line, " ") && strings.HasSuffix(trimmed, ":") {
+			first := strings.Split(strings.TrimSuffix(trimmed, ":"), ",")[0]
+			name, _ := splitNameSpec(first)
+			if name == "" || name == "__metadata" {
+				cur = -1
+				continue
+			}
+			deps = append(deps, dependency{Name: name})
+			cur = len(deps) - 1
+			continue
+		}
+		if cur < 0 {
+			continue
+		}
+		key, val, ok := strings.Cut(trimmed, " ")
+		if !ok {
+			continue
+		}
+		val = strings.Trim(strings.TrimSpace(val), `"`)
+		switch strings.TrimSuffix(key, ":") {
+		case "version":
+			deps[cur].Spec = val
+		case "resolved":
+			deps[cur].PrivateRegistry = privateRegistryHost(val)
+		}
+	}
+	return deps
+}
+
+func parsePNPMLock(body []byte) []dependency {
+	var deps []dependency
+	inPackages := false
+	cur := -1
+	sc := lineScanner(body)
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, " ") {
+			inPackages = strings.HasPrefix(line, "packages:")
+			cur = -1
+			continue
+		}
+		if !inPackages {
+			continue
+		}
+		if strings.HasPrefix(line, "  ") && !strings.HasPrefix(line, "   ") {
+			key := strings.Trim(strings.TrimSuffix(strings.TrimSpace(line), ":"), `'"`)
+			key = strings.TrimPrefix(key, "/")
+			if i := strings.IndexByte(key, '('); i > 0 {
+				key = key[:i]
+			}
+			name, ver := splitNameSpec(key)
+			// lockfile v5: /name/1.2.3_peer@1.0.0
+			if i := strings.LastIndex(key, "/"); i > 0 && i+1 < len(key) && key[i+1] >= '0' && key[i+1] <= '9' {
+				name, ver = key[:i], key[i+1:]
+				if j := strings.IndexByte(ver, '_'); j > 0 {
+					ver = ver[:j]
+				}
+			}
+			deps = append(deps, dependency{Name: name, Spec: ver})
+			cur = len(deps) - 1
+			continue
+		}
+		if cur >= 0 {
+			if m := pnpmTarballRe.FindStringSubmatch(line); m != nil {
+				deps[cur].PrivateRegistry = privateRegistryHost(m[1])
+			}
+			if strings.TrimSpace(line) == "dev: true" {
+				deps[cur].Type = depDev
+			}
+		}
+	}
+	return deps
+}
+
+func parsePipfileLock(body []byte) ([]dependency, error) {
+	type entry struct {
+		Version string `json:"version"`
+		Index   string `json:"index"`
+	}
+	var lock struct {
+		Meta struct {
+			Sources []struct {
+				Name string `json:"name"`
+				URL  string `json:"url"`
+			} `json:"sources"`
+		} `json:"_meta"`
+		Default map[string]entry `json:"default"`
+		Develop map[string]entry `json:"develop"`
+	}
+	if err := json.Unmarshal(body, &lock); err != nil {
+		return nil, err
+	}
+	sources := make(map[string]string)
+	var fallback string
+	for _, s := range lock.Meta.Sources {
+		host := privateRegistryHost(s.URL)
+		sources[s.Name] = host
+		if fallback == "" && host != "" {
+			fallback = host
+		}
+	}
+	var deps []dependency
+	for i, group := range []map[string]entry{lock.Default, lock.Develop} {
+		var typ depType
+		if i == 1 {
+			typ = depDev
+		}
+		for name, e := range group {
+			reg := fallback
+			if e.Index != "" {
+				reg = sources[e.Index]
+			}
+			deps = append(deps, dependency{Name: name, Spec: e.Version, PrivateRegistry: reg, Type: typ})
+		}
+	}
+	return deps, nil
+}
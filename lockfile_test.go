@@ -0,0 +1,345 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// sortDeps orders parser output, which follows map iteration for JSON
+// lockfiles, so tests can compare it.
+func sortDeps(deps []dependency) []dependency {
+	sort.Slice(deps, func(i, j int) bool {
+		if deps[i].Name != deps[j].Name {
+			return deps[i].Name < deps[j].Name
+		}
+		return deps[i].Spec < deps[j].Spec
+	})
+	return deps
+}
+
+func TestPrivateRegistryHost(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"https://registry.npmjs.org/lodash/-/lodash-4.17.21.tgz", ""},
+		{"https://registry.yarnpkg.com/react/-/react-18.2.0.tgz#sha1", ""},
+		{"https://npm.acme.corp/@acme/ui/-/ui-1.0.0.tgz", "npm.acme.corp"},
+		{"https://NPM.Acme.Corp:8443/x.tgz", "npm.acme.corp"},
+		{"git+ssh://git@github.com/acme/tool.git#abc", ""},
+		{"git+https://git.acme.corp/acme/tool.git", "git.acme.corp"},
+		{"https://codeload.github.com/acme/tool/tar.gz/abc", ""},
+		{"https://pypi.org/simple", ""},
+		{"https://nexus.acme.corp/repository/pypi/simple", "nexus.acme.corp"},
+		{"file:../local", ""},
+		{"", ""},
+		{"not a url", ""},
+	}
+	for _, tt := range tests {
+		if got := privateRegistryHost(tt.in); got != tt.want {
+			t.Errorf("privateRegistryHost(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseNPMLock(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []dependency
+	}{
+		{
+			name: "lockfile v3",
+			body: `{
+  "name": "app",
+  "lockfileVersion": 3,
+  "requires": true,
+  "packages": {
+    "": {"name": "app", "dependencies": {"@acme/ui": "^1.0.0", "lodash": "^4.17.21"}},
+    "node_modules/@acme/ui": {"version": "1.0.3", "resolved": "https://npm.acme.corp/@acme/ui/-/ui-1.0.3.tgz"},
+    "node_modules/lodash": {"version": "4.17.21", "resolved": "https://registry.npmjs.org/lodash/-/lodash-4.17.21.tgz"},
+    "node_modules/jest": {"version": "29.7.0", "dev": true},
+    "node_modules/fsevents": {"version": "2.3.3", "devOptional": true, "optional": true},
+    "node_modules/@acme/ui/node_modules/acme-internal": {"version": "0.1.0"},
+    "packages/local": {"version": "1.0.0"}
+  }
+}`,
+			want: []dependency{
+				{Name: "@acme/ui", Spec: "1.0.3", PrivateRegistry: "npm.acme.corp"},
+				{Name: "acme-internal", Spec: "0.1.0"},
+				{Name: "fsevents", Spec: "2.3.3", Type: depDev},
+				{Name: "jest", Spec: "29.7.0", Type: depDev},
+				{Name: "lodash", Spec: "4.17.21"},
+			},
+		},
+		{
+			name: "lockfile v2 keeps the v1 section for old npm",
+			body: `{
+  "name": "app",
+  "lockfileVersion": 2,
+  "packages": {
+    "": {"name": "app", "dependencies": {"acme-core": "^3.0.0"}},
+    "node_modules/acme-core": {"version": "3.1.0", "resolved": "https://npm.acme.corp/acme-core/-/acme-core-3.1.0.tgz", "dependencies": {"ms": "^2.1.0"}},
+    "node_modules/ms": {"version": "2.1.3"}
+  },
+  "dependencies": {
+    "acme-core": {"version": "3.1.0", "requires": {"ms": "^2.1.0"}},
+    "ms": {"version": "2.1.3"}
+  }
+}`,
+			want: []dependency{
+				{Name: "acme-core", Spec: "3.1.0", PrivateRegistry: "npm.acme.corp"},
+				{Name: "ms", Spec: "2.1.3"},
+			},
+		},
+		{
+			name: "lockfile v1 with nested dependencies",
+			body: `{
+  "name": "app",
+  "lockfileVersion": 1,
+  "dependencies": {
+    "express": {
+      "version": "4.18.2",
+      "resolved": "https://registry.npmjs.org/express/-/express-4.18.2.tgz",
+      "dependencies": {
+        "acme-helper": {"version": "2.0.0", "resolved": "https://artifactory.acme.corp/api/npm/npm/acme-helper/-/acme-helper-2.0.0.tgz"}
+      }
+    },
+    "mocha": {"version": "10.2.0", "dev": true}
+  }
+}`,
+			want: []dependency{
+				{Name: "acme-helper", Spec: "2.0.0", PrivateRegistry: "artifactory.acme.corp"},
+				{Name: "express", Spec: "4.18.2"},
+				{Name: "mocha", Spec: "10.2.0", Type: depDev},
+			},
+		},
+		{
+			name: "no packages",
+			body: `{"lockfileVersion": 3, "packages": {"": {"name": "app"}}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseNPMLock([]byte(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := sortDeps(got); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := parseNPMLock([]byte(`{"packages": `)); err == nil {
+		t.Error("truncated lockfile parsed without an error")
+	}
+}
+
+func TestParseYarnLock(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []dependency
+	}{
+		{
+			name: "yarn v1",
+			body: `# THIS IS AN AUTOGENERATED FILE. DO NOT EDIT THIS FILE DIRECTLY.
+# yarn lockfile v1
+
+
+"@acme/ui@^1.0.0", "@acme/ui@^1.0.2":
+  version "1.0.3"
+  resolved "https://npm.acme.corp/@acme/ui/-/ui-1.0.3.tgz#0123abcd"
+  integrity sha512-xxxx
+  dependencies:
+    lodash "^4.17.0"
+
+lodash@^4.17.0, lodash@^4.17.21:
+  version "4.17.21"
+  resolved "https://registry.yarnpkg.com/lodash/-/lodash-4.17.21.tgz#679591c5"
+`,
+			want: []dependency{
+				{Name: "@acme/ui", Spec: "1.0.3", PrivateRegistry: "npm.acme.corp"},
+				{Name: "lodash", Spec: "4.17.21"},
+			},
+		},
+		{
+			name: "yarn berry",
+			body: `__metadata:
+  version: 6
+  cacheKey: 8
+
+"@acme/ui@npm:^1.0.0":
+  version: 1.0.3
+  resolution: "@acme/ui@npm:1.0.3"
+  checksum: abcd
+  languageName: node
+  linkType: hard
+
+"left-pad@npm:1.3.0":
+  version: 1.3.0
+  resolution: "left-pad@npm:1.3.0"
+`,
+			want: []dependency{
+				{Name: "@acme/ui", Spec: "1.0.3"},
+				{Name: "left-pad", Spec: "1.3.0"},
+			},
+		},
+		{
+			name: "CRLF line endings",
+			body: "lodash@^4.17.21:\r\n  version \"4.17.21\"\r\n  resolved \"https://yarn.acme.corp/lodash.tgz\"\r\n",
+			want: []dependency{{Name: "lodash", Spec: "4.17.21", PrivateRegistry: "yarn.acme.corp"}},
+		},
+		{
+			name: "comments only",
+			body: "# yarn lockfile v1\n\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sortDeps(parseYarnLock([]byte(tt.body))); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePNPMLock(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []dependency
+	}{
+		{
+			name: "lockfile v9",
+			body: `lockfileVersion: '9.0'
+
+importers:
+
+  .:
+    dependencies:
+      '@acme/ui':
+        specifier: ^1.0.0
+        version: 1.0.3(react@18.2.0)
+
+packages:
+
+  '@acme/ui@1.0.3':
+    resolution: {integrity: sha512-xxxx, tarball: https://npm.acme.corp/@acme/ui/-/ui-1.0.3.tgz}
+    peerDependencies:
+      react: ^18
+
+  react@18.2.0:
+    resolution: {integrity: sha512-yyyy}
+    engines: {node: '>=0.10.0'}
+
+snapshots:
+
+  '@acme/ui@1.0.3(react@18.2.0)':
+    dependencies:
+      react: 18.2.0
+`,
+			want: []dependency{
+				{Name: "@acme/ui", Spec: "1.0.3", PrivateRegistry: "npm.acme.corp"},
+				{Name: "react", Spec: "18.2.0"},
+			},
+		},
+		{
+			name: "lockfile v5 with peer suffixes and dev flags",
+			body: `lockfileVersion: 5.4
+
+specifiers:
+  left-pad: ^1.3.0
+
+packages:
+
+  /left-pad/1.3.0:
+    resolution: {integrity: sha512-zzzz}
+    dev: false
+
+  /@acme/build-tools/2.1.0_typescript@5.2.2:
+    resolution: {integrity: sha512-wwww, tarball: 'https://npm.acme.corp/@acme/build-tools/-/build-tools-2.1.0.tgz'}
+    dev: true
+`,
+			want: []dependency{
+				{Name: "@acme/build-tools", Spec: "2.1.0", PrivateRegistry: "npm.acme.corp", Type: depDev},
+				{Name: "left-pad", Spec: "1.3.0"},
+			},
+		},
+		{
+			name: "no packages section",
+			body: "lockfileVersion: '9.0'\n\nimporters:\n\n  .: {}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sortDeps(parsePNPMLock([]byte(tt.body))); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePipfileLock(t *testing.T) {
+	body := `{
+    "_meta": {
+        "hash": {"sha256": "abcd"},
+        "pipfile-spec": 6,
+        "sources": [
+            {"name": "pypi", "url": "https://pypi.org/simple", "verify_ssl": true},
+            {"name": "internal", "url": "https://nexus.acme.corp/repository/pypi/simple", "verify_ssl": true}
+        ]
+    },
+    "default": {
+        "requests": {"hashes": [], "index": "pypi", "version": "==2.31.0"},
+        "acme-core": {"hashes": [], "index": "internal", "version": "==1.4.0"},
+        "six": {"hashes": [], "version": "==1.16.0"}
+    },
+    "develop": {
+        "pytest": {"hashes": [], "index": "pypi", "version": "==7.4.3"}
+    }
+}`
+	want := []dependency{
+		{Name: "acme-core", Spec: "==1.4.0", PrivateRegistry: "nexus.acme.corp"},
+		{Name: "pytest", Spec: "==7.4.3", Type: depDev},
+		{Name: "requests", Spec: "==2.31.0"},
+		// without an index the first private source is the one pip may use
+		{Name: "six", Spec: "==1.16.0", PrivateRegistry: "nexus.acme.corp"},
+	}
+	got, err := parsePipfileLock([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sortDeps(got); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestParseLockfileDispatch(t *testing.T) {
+	tests := []struct {
+		url  string
+		lock bool
+		lang language
+	}{
+		{"https://example.com/package-lock.json", true, langJS},
+		{"https://example.com/app/npm-shrinkwrap.json", true, langJS},
+		{"https://example.com/yarn.lock", true, langJS},
+		{"https://example.com/pnpm-lock.yaml", true, langJS},
+		{"https://example.com/Pipfile.lock", true, langPython},
+		{"https://example.com/package.json", false, ""},
+		{"https://example.com/Pipfile", false, ""},
+	}
+	for _, tt := range tests {
+		if got := isLockfile(tt.url); got != tt.lock {
+			t.Errorf("isLockfile(%q) = %v, want %v", tt.url, got, tt.lock)
+		}
+		if !tt.lock {
+			continue
+		}
+		body := "{}"
+		if _, lang, err := parseLockfile(tt.url, []byte(body)); err != nil || lang != tt.lang {
+			t.Errorf("parseLockfile(%q) = %q, %v, want %q", tt.url, lang, err, tt.lang)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+)
+
+// lookups runs each keyed registry lookup at most once per run: concurrent
+// callers for the same key wait for the first one and share its result, so a
+// package referenced by hundreds of bundles costs a single request. Finished
+// lookups live in a bounded LRU, so on very large scans an evicted package
+// may be looked up again.
+type lookups[V any] struct {
+	cache *lruCache[V]
+	// shared, when set, is consulted before fn and fed its results
+	shared sharedStore[V]
+
+	mu       sync.Mutex
+	inflight map[string]chan struct{}
+}
+
+func newLookups[V any](name string) *lookups[V] {
+	return &lookups[V]{cache: newLRU[V](name), inflight: make(map[string]chan struct{})}
+}
+
+// do returns the result for key, calling fn if nobody has yet. fn also
+// returns the estimated size of the result in bytes.
+func (l *lookups[V]) do(key string, fn func() (V, int)) V {
+	for {
+		l.mu.Lock()
+		if v, ok := l.cache.peek(key); ok {
+			l.mu.Unlock()
+			l.cache.record(true)
+			return v
+		}
+		done, waiting := l.inflight[key]
+		if !waiting {
+			done = make(chan struct{})
+			l.inflight[key] = done
+		}
+		l.mu.Unlock()
+
+		if waiting {
+			<-done
+			// the result may have been evicted already, in which case
+			// the loop looks it up again
+			if v, ok := l.cache.peek(key); ok {
+				l.cache.record(true)
+				return v
+			}
+			continue
+		}
+
+		l.cache.record(false)
+		v, size := l.fetch(key, fn)
+		l.mu.Lock()
+		l.cache.add(key, v, size)
+		delete(l.inflight, key)
+		l.mu.Unlock()
+		close(done)
+		return v
+	}
+}
+
+func (l *lookups[V]) fetch(key string, fn func() (V, int)) (V, int) {
+	if l.shared == nil {
+		return fn()
+	}
+	if v, ok := l.shared.load(key); ok {
+		return v, 0
+	}
+	v, size := fn()
+	l.shared.store(key, v)
+	return v, size
+}
+
+// sharedStore is a lookup cache shared by every DCHero process pointed at it
+// (-cache redis://...).
+type sharedStore[V any] interface {
+	load(key string) (V, bool)
+	store(key string, v V)
+}
+
+func (l *lookups[V]) reset() {
+	l.cache.reset()
+}
+
+type packageStatus struct {
+	unclaimed bool
+	status    int
+}
+
+// shareable reports whether a package answer is conclusive enough to keep
+// and hand to other scanners; errors, rate limits and odd statuses are
+// forgotten and retried by whoever asks next.
+func shareable(v packageStatus) bool {
+	switch v.status {
+	case http.StatusOK, http.StatusFound, http.StatusNotFound, http.StatusGone:
+		return true
+	}
+	return false
+}
+
+func sharedPackageStatus(status int) packageStatus {
+	return packageStatus{unclaimed: status != http.StatusOK && status != http.StatusFound, status: status}
+}
+
+var (
+	packageLookups  = newLookups[packageStatus]("package")
+	npmMetaLookups  = newLookups[metaResult[npmPackument]]("npm metadata")
+	pypiMetaLookups = newLookups[metaResult[pypiProject]]("pypi metadata")
+)
+
+type metaResult[T any] struct {
+	m   *T
+	err error
+}
+
+func lookupKey(pkg string, lang language) string {
+	return registryFor(lang) + ":" + pkg
+}
+
+// forget drops the result for key so the next caller looks it up again.
+func (l *lookups[V]) forget(key string) {
+	l.cache.remove(key)
+}
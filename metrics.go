@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// metricVec is a Prometheus counter or gauge with at most one label.
+type metricVec struct {
+	name, help, typ, label string
+
+	mu sync.Mutex
+	m  map[string]float64
+}
+
+func newMetric(typ, name, label, help string) *metricVec {
+	return &metricVec{name: name, help: help, typ: typ, label: label, m: make(map[string]float64)}
+}
+
+func (v *metricVec) add(label string, n float64) {
+	v.mu.Lock()
+	v.m[label] += n
+	v.mu.Unlock()
+}
+
+func (v *metricVec) inc(label string) { v.add(label, 1) }
+
+func (v *metricVec) set(label string, n float64) {
+	v.mu.Lock()
+	v.m[label] = n
+	v.mu.Unlock()
+}
+
+// reset drops every series, for gauges recomputed from scratch.
+func (v *metricVec) reset() {
+	v.mu.Lock()
+	v.m = make(map[string]float64)
+	v.mu.Unlock()
+}
+
+// values returns a copy of every series.
+func (v *metricVec) values() map[string]float64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	out := make(map[string]float64, len(v.m))
+	for k, n := range v.m {
+		out[k] = n
+	}
+	return out
+}
+
+func (v *metricVec) write(w io.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.name, v.help, v.name, v.typ)
+	keys := make([]string, 0, len(v.m))
+	for k := range v.m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		val := strconv.FormatFloat(v.m[k], 'f', -1, 64)
+		if v.label == "" {
+			fmt.Fprintf(w, "%s %s\n", v.name, val)
+		} else {
+			fmt.Fprintf(w, "%s{%s=%q} %s\n", v.name, v.label, k, val)
+		}
+	}
+}
+
+var (
+	metricURLs           = newMetric("counter", "dchero_urls_scanned_total", "", "URLs fetched and scanned.")
+	metricURLErrors      = newMetric("counter", "dchero_url_errors_total", "category", "URLs that failed, by error category.")
+	metricPackages       = newMetric("counter", "dchero_packages_checked_total", "registry", "Dependencies checked, by registry.")
+	metricRegistryReqs   = newMetric("counter", "dchero_registry_requests_total", "registry", "Package lookups sent to registries.")
+	metricRegistryErrors = newMetric("counter", "dchero_registry_errors_total", "registry", "Registry lookups that failed or were rate limited.")
+	metricFindings       = newMetric("gauge", "dchero_findings", "confidence", "Findings of the last completed round, by confidence.")
+	metricRounds         = newMetric("counter", "dchero_rounds_total", "", "Completed daemon rounds.")
+	metricRoundSeconds   = newMetric("gauge", "dchero_last_round_duration_seconds", "", "Duration of the last completed round.")
+	metricRoundTime      = newMetric("gauge", "dchero_last_round_timestamp_seconds", "", "Unix time the last round completed.")
+	metricCacheHits      = newMetric("counter", "dchero_cache_hits_total", "cache", "Registry cache hits.")
+	metricCacheMisses    = newMetric("counter", "dchero_cache_misses_total", "cache", "Registry cache misses.")
+	metricCacheEvictions = newMetric("counter", "dchero_cache_evictions_total", "cache", "Registry cache evictions.")
+	metricCacheEntries   = newMetric("gauge", "dchero_cache_entries", "cache", "Entries held per registry cache.")
+
+	allMetrics = []*metricVec{metricURLs, metricURLErrors, metricPackages, metricRegistryReqs, metricRegistryErrors,
+		metricFindings, metricRounds, metricRoundSeconds, metricRoundTime,
+		metricCacheHits, metricCacheMisses, metricCacheEvictions, metricCacheEntries}
+)
+
+// recordRound updates the per-round metrics from a finished scan.
+func recordRound(results []scanResult, took float64, at int64) {
+	metricFindings.reset()
+	for _, c := range []confidence{confHigh, confMedium, confLow} {
+		metricFindings.set(string(c), 0)
+	}
+	for _, r := range results {
+		metricURLs.inc("")
+		if r.err != nil {
+			metricURLErrors.inc(string(errorCategoryOf(r.err)))
+		}
+		for _, v := range r.vulns {
+			metricFindings.add(string(v.Confidence), 1)
+		}
+	}
+	metricRounds.inc("")
+	metricRoundSeconds.set("", took)
+	metricRoundTime.set("", float64(at))
+}
+
+func serveMetrics(w http.ResponseWriter, _ *http.Request) {
+	for _, c := range allCaches() {
+		name, hits, misses, evictions, entries := c.counters()
+		metricCacheHits.set(name, float64(hits))
+		metricCacheMisses.set(name, float64(misses))
+		metricCacheEvictions.set(name, float64(evictions))
+		metricCacheEntries.set(name, float64(entries))
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	for _, m := range allMetrics {
+		m.write(w)
+	}
+}
+
+func startMetricsServer(addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", serveMetrics)
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			fmt.Fprintf(os.Stderr, "metrics server: %v\n", err)
+		}
+	}()
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+var (
+	npmOrgPackagesURL  = "https://registry.npmjs.org/-/org/%s/package"
+	npmUserPackagesURL = "https://registry.npmjs.org/-/user/%s/package"
+	npmSearchURL       = "https://registry.npmjs.org/-/v1/search?size=20&text=scope:%s"
+
+	npmScopeLookups = newLookups[string]("npm scope")
+
+	// mail providers that say nothing about who owns a scope
+	freeMailDomains = toSet([]string{"gmail.com", "googlemail.com", "outlook.com", "hotmail.com", "live.com", "yahoo.com",
+		"icloud.com", "me.com", "protonmail.com", "proton.me", "qq.com", "163.com", "126.com", "yandex.ru", "mail.ru", "gmx.de"})
+)
+
+const npmScopeFree = "is not registered on npm, anyone can create it"
+
+// npmScopeDetail describes who holds the scope of an unclaimed scoped npm
+// package. A free scope means anyone can publish the name; a taken one means
+// only its members can, which matters only if they aren't the target.
+func npmScopeDetail(pkg string) string {
+	scope, _, ok := strings.Cut(strings.TrimPrefix(pkg, "@"), "/")
+	if !ok || !strings.HasPrefix(pkg, "@") || scope == "" {
+		return ""
+	}
+	d := npmScopeLookups.do(scope, func() (string, int) {
+		d := describeNPMScope(scope)
+		return d, len(d)
+	})
+	if d == "" {
+		// the registry could not be asked; try again for the next package
+		npmScopeLookups.forget(scope)
+	}
+	return d
+}
+
+func describeNPMScope(scope string) string {
+	h := map[string]string{"User-Agent": randomUA(), "Accept": "application/json"}
+	var owner string
+	var packages map[string]string
+	for _, c := range []struct{ kind, tmpl string }{{"org", npmOrgPackagesURL}, {"user", npmUserPackagesURL}} {
+		body, status, err := httpGET(fmt.Sprintf(c.tmpl, url.PathEscape(scope)), h)
+		if err != nil {
+			return ""
+		}
+		if status == http.StatusNotFound {
+			continue
+		}
+		if status != http.StatusOK || json.Unmarshal(body, &packages) != nil {
+			return ""
+		}
+		owner = c.kind
+		break
+	}
+	if owner == "" {
+		return fmt.Sprintf("scope @%s %s", scope, npmScopeFree)
+	}
+	if len(packages) == 0 {
+		return fmt.Sprintf("scope @%s exists (npm %s, no public packages: reserved or squatted)", scope, owner)
+	}
+
+	signals := []string{fmt.Sprintf("npm %s, %d public packages", owner, len(packages))}
+	domains, repos, last := npmScopeSearch(scope)
+	if len(domains) > 0 {
+		signals = append(signals, "maintainer email domains "+strings.Join(domains, ", "))
+	}
+	if len(repos) > 0 {
+		signals = append(signals, "repositories "+strings.Join(repos, ", "))
+	}
+	if checked, signed := npmScopeProvenance(packages); checked > 0 {
+		signals = append(signals, fmt.Sprintf("provenance on %d of %d latest releases", signed, checked))
+	}
+	if last != "" {
+		signals = append(signals, "last publish "+last)
+	}
+	return fmt.Sprintf("scope @%s exists (%s)", scope, strings.Join(signals, "; "))
+}
+
+// npmScopeSearch returns the company mail domains and source owners behind
+// the scope's packages, and the date of its latest publish.
+func npmScopeSearch(scope string) (domains, repos []string, last string) {
+	body, status, err := httpGET(fmt.Sprintf(npmSearchURL, url.QueryEscape(scope)), map[string]string{"User-Agent": randomUA(), "Accept": "application/json"})
+	if err != nil || status != http.StatusOK {
+		return nil, nil, ""
+	}
+	var res struct {
+		Objects []struct {
+			Package struct {
+				Date        string          `json:"date"`
+				Publisher   npmSearchUser   `json:"publisher"`
+				Maintainers []npmSearchUser `json:"maintainers"`
+				Links       struct {
+					Repository string `json:"repository"`
+				} `json:"links"`
+			} `json:"package"`
+		} `json:"objects"`
+	}
+	if json.Unmarshal(body, &res) != nil {
+		return nil, nil, ""
+	}
+	ds, rs := make(map[string]struct{}), make(map[string]struct{})
+	for _, o := range res.Objects {
+		p := o.Package
+		for _, u := range append(p.Maintainers, p.Publisher) {
+			if _, domain, ok := strings.Cut(strings.ToLower(u.Email), "@"); ok && domain != "" {
+				if _, free := freeMailDomains[domain]; !free {
+					ds[domain] = struct{}{}
+				}
+			}
+		}
+		if r, err := url.Parse(p.Links.Repository); err == nil && r.Host != "" {
+			if owner, _, _ := strings.Cut(strings.Trim(r.Path, "/"), "/"); owner != "" {
+				rs[strings.ToLower(r.Host)+"/"+owner] = struct{}{}
+			}
+		}
+		if d := p.Date; len(d) >= 10 && d[:10] > last {
+			last = d[:10]
+		}
+	}
+	return sortedKeys(ds), sortedKeys(rs), last
+}
+
+type npmSearchUser struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+// npmScopeProvenance checks the latest release of up to three of the scope's
+// packages for a provenance attestation, which only CI-published releases
+// of an organization usually have.
+func npmScopeProvenance(packages map[string]string) (checked, signed int) {
+	names := sortedKeys(packages)
+	for _, name := range names[:min(3, len(names))] {
+		m, err := fetchNPMMeta(name)
+		if err != nil {
+			continue
+		}
+		var v struct {
+			Dist struct {
+				Attestations json.RawMessage `json:"attestations"`
+			} `json:"dist"`
+		}
+		if json.Unmarshal(m.Versions[m.DistTags["latest"]], &v) != nil {
+			continue
+		}
+		checked++
+		if len(v.Dist.Attestations) > 0 {
+			signed++
+		}
+	}
+	return checked, signed
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var nucleiSeverity = map[confidence]string{confHigh: "high", confMedium: "medium", confLow: "low"}
+
+type nucleiInfo struct {
+	Name        string   `json:"name"`
+	Author      []string `json:"author"`
+	Tags        []string `json:"tags"`
+	Description string   `json:"description"`
+	Reference   []string `json:"reference"`
+	Severity    string   `json:"severity"`
+	Remediation string   `json:"remediation,omitempty"`
+}
+
+// nucleiResult mirrors the fields of nuclei's -jsonl export that result
+// pipelines key on.
+type nucleiResult struct {
+	TemplateID       string     `json:"template-id"`
+	Info             nucleiInfo `json:"info"`
+	Type             string     `json:"type"`
+	Host             string     `json:"host"`
+	MatchedAt        string     `json:"matched-at"`
+	ExtractedResults []string   `json:"extracted-results"`
+	Timestamp        string     `json:"timestamp"`
+	MatcherStatus    bool       `json:"matcher-status"`
+}
+
+func nucleiFinding(v vuln, u string, now time.Time) nucleiResult {
+	host := u
+	if p, err := url.Parse(u); err == nil {
+		host = p.Scheme + "://" + p.Host
+	}
+	reg := registryFor(v.Language)
+	return nucleiResult{
+		TemplateID: "dchero-dependency-confusion",
+		Info: nucleiInfo{
+			Name:        v.title(),
+			Author:      []string{"dchero"},
+			Tags:        []string{"dependency-confusion", "supply-chain", reg, string(v.Kind)},
+			Description: v.summary(),
+			Reference:   []string{registryURL(v.Package, v.Language)},
+			Severity:    nucleiSeverity[v.severity()],
+			Remediation: remediation[v.Language],
+		},
+		Type:             "http",
+		Host:             host,
+		MatchedAt:        u,
+		ExtractedResults: []string{v.Package},
+		Timestamp:        now.Format(time.RFC3339Nano),
+		MatcherStatus:    true,
+	}
+}
+
+func printNuclei(v vuln, u string) {
+	b, err := json.Marshal(nucleiFinding(v, u, time.Now()))
+	if err != nil {
+		return
+	}
+	fmt.Println(string(b))
+}
+
+var templateIDRe = regexp.MustCompile(`[^a-z0-9]+`)
+
+func yamlString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+// The verification template re-checks the registry: run it with
+// -u <registry base> and it matches while the name is still unclaimed.
+const nucleiTemplate = `id: %[1]s
+
+info:
+  name: %[2]s
+  author: dchero
+  severity: %[3]s
+  description: %[4]s
+  reference:
+    - %[5]s
+  metadata:
+    registry: %[6]s
+    evidence: %[7]s
+  tags: dependency-confusion,supply-chain,%[8]s
+
+http:
+  - method: GET
+    path:
+      - %[9]s
+    matchers:
+      - type: status
+        status:
+          - 404
+`
+
+func nucleiTemplateFor(g groupedFinding) (id, body string) {
+	id = "dchero-" + g.Registry + "-" + strings.Trim(templateIDRe.ReplaceAllString(strings.ToLower(g.Package), "-"), "-")
+	check := registryURL(g.Package, g.Language)
+	base, pth := check, ""
+	if p, err := url.Parse(check); err == nil {
+		base = p.Scheme + "://" + p.Host
+		pth = p.EscapedPath()
+	}
+	body = fmt.Sprintf(nucleiTemplate,
+		id,
+		yamlString(g.title()),
+		nucleiSeverity[g.severity()],
+		yamlString(fmt.Sprintf("%s is referenced by the target but not registered on %s.", g.Package, g.Registry)),
+		yamlString(check),
+		yamlString(base),
+		yamlString(strings.Join(g.URLs, " ")),
+		g.Registry,
+		yamlString("{{BaseURL}}"+pth),
+	)
+	return id, body
+}
+
+func writeNucleiTemplates(dir string, results []scanResult) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, g := range groupFindings(results) {
+		if g.Kind != kindUnclaimed {
+			continue
+		}
+		id, body := nucleiTemplateFor(g)
+		if err := os.WriteFile(filepath.Join(dir, id+".yaml"), []byte(body), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// runners that install the package they are given from the registry when it
+// is not already in node_modules
+var npxRe = regexp.MustCompile(`(?:^|[\s;&|(])(npx|bunx|pnpm\s+dlx|yarn\s+dlx)\s+([^;&|()]+)`)
+
+// packageJSONDeps returns every package a package.json pulls from the
+// registry: the dependency lists, overrides and resolutions, the package
+// manager corepack fetches and the packages scripts run through npx.
+func packageJSONDeps(pj packageJSON) []dependency {
+	var deps []dependency
+	declared := make(map[string]struct{})
+	for _, m := range []map[string]string{pj.Dependencies, pj.PeerDependencies, pj.OptionalDependencies} {
+		for k, v := range m {
+			deps = append(deps, dependency{Name: k, Spec: v})
+			declared[k] = struct{}{}
+		}
+	}
+	for k, v := range pj.DevDependencies {
+		deps = append(deps, dependency{Name: k, Spec: v, Type: depDev})
+		declared[k] = struct{}{}
+	}
+	for _, raw := range []json.RawMessage{pj.BundledDependencies, pj.BundleDependencies} {
+		// true bundles everything in dependencies
+		var names []string
+		if json.Unmarshal(raw, &names) == nil {
+			deps = append(deps, namesToDeps(names)...)
+		}
+	}
+	deps = append(deps, overrideDeps(pj.Overrides)...)
+	for k, v := range pj.Resolutions {
+		if d, ok := overrideTarget(resolutionName(k), v); ok {
+			deps = append(deps, d)
+		}
+	}
+	if pm := strings.TrimSpace(pj.PackageManager); pm != "" && !strings.Contains(pm, "://") {
+		name, spec := splitNameSpec(pm)
+		spec, _, _ = strings.Cut(spec, "+")
+		deps = append(deps, dependency{Name: name, Spec: spec, Via: "packageManager field", Type: depDev})
+	}
+	return append(deps, npxDeps(pj.Scripts, declared)...)
+}
+
+// overrideTarget returns the package an override or resolution installs, if
+// it comes from the registry.
+func overrideTarget(name, spec string) (dependency, bool) {
+	spec = strings.TrimSpace(spec)
+	if real, ok := strings.CutPrefix(spec, "npm:"); ok {
+		name, spec = splitNameSpec(real)
+	}
+	// $name refers to the version in dependencies
+	if strings.HasPrefix(spec, "$") {
+		spec = ""
+	}
+	if name == "" || (spec != "" && !isRegistrySpec(spec)) {
+		return dependency{}, false
+	}
+	return dependency{Name: name, Spec: spec}, true
+}
+
+// overrideDeps walks npm overrides, which nest: {"a": {".": "1.0.0", "b": "2.0.0"}}.
+func overrideDeps(raw json.RawMessage) []dependency {
+	var m map[string]json.RawMessage
+	if json.Unmarshal(raw, &m) != nil {
+		return nil
+	}
+	var deps []dependency
+	for k, v := range m {
+		if k == "." {
+			continue
+		}
+		name, _ := splitNameSpec(k)
+		var spec string
+		if json.Unmarshal(v, &spec) != nil {
+			var nested map[string]json.RawMessage
+			if json.Unmarshal(v, &nested) == nil {
+				json.Unmarshal(nested["."], &spec)
+			}
+			deps = append(deps, overrideDeps(v)...)
+		}
+		if d, ok := overrideTarget(name, spec); ok {
+			deps = append(deps, d)
+		}
+	}
+	return deps
+}
+
+// resolutionName returns the package a yarn resolution key pins:
+// "**/a/@scope/b" pins @scope/b.
+func resolutionName(key string) string {
+	parts := strings.Split(strings.TrimPrefix(key, "**/"), "/")
+	name := parts[len(parts)-1]
+	if len(parts) > 1 && strings.HasPrefix(parts[len(parts)-2], "@") {
+		name = parts[len(parts)-2] + "/" + name
+	}
+	name, _ = splitNameSpec(name)
+	return name
+}
+
+// npxDeps returns the packages scripts run through npx and friends. Names
+// the package depends on are skipped: npx finds those in node_modules.
+func npxDeps(scripts map[string]string, declared map[string]struct{}) []dependency {
+	keys := make([]string, 0, len(scripts))
+	for k := range scripts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var deps []dependency
+	for _, k := range keys {
+		for _, m := range npxRe.FindAllStringSubmatch(scripts[k], -1) {
+			via := strings.Join(strings.Fields(m[1]), " ") + " in scripts." + k
+			for _, pkg := range npxPackages(strings.Fields(m[2])) {
+				name, spec := splitNameSpec(pkg)
+				if _, ok := declared[name]; ok || !npxName(name) {
+					continue
+				}
+				deps = append(deps, dependency{Name: name, Spec: spec, Via: via, Type: depDev})
+			}
+		}
+	}
+	return deps
+}
+
+// npxPackages returns the packages of one npx invocation: those given with
+// -p/--package, or else the command itself.
+func npxPackages(args []string) []string {
+	var pkgs []string
+	for i := 0; i < len(args); i++ {
+		a := strings.Trim(args[i], `"'`)
+		switch {
+		case a == "-p" || a == "--package":
+			if i+1 < len(args) {
+				i++
+				pkgs = append(pkgs, strings.Trim(args[i], `"'`))
+			}
+		case strings.HasPrefix(a, "--package="):
+			pkgs = append(pkgs, strings.TrimPrefix(a, "--package="))
+		case a == "-c" || a == "--call":
+			return pkgs
+		case strings.HasPrefix(a, "-"):
+		default:
+			if len(pkgs) == 0 {
+				pkgs = append(pkgs, a)
+			}
+			return pkgs
+		}
+	}
+	return pkgs
+}
+
+// npxName reports whether an npx argument names a registry package rather
+// than a path, URL or variable.
+func npxName(name string) bool {
+	if name == "" || strings.ContainsAny(name, ":$\\=") || strings.HasPrefix(name, ".") {
+		return false
+	}
+	if strings.HasPrefix(name, "@") {
+		return strings.Count(name, "/") == 1
+	}
+	return !strings.Contains(name, "/")
+}
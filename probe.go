@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var probePaths = []string{
+	"/package.json",
+	"/package-lock.json",
+	"/npm-shrinkwrap.json",
+	"/yarn.lock",
+	"/pnpm-lock.yaml",
+	"/bower.json",
+	"/requirements.txt",
+	"/Pipfile",
+	"/Pipfile.lock",
+	"/pyproject.toml",
+	"/setup.py",
+	"/setup.cfg",
+	"/composer.json",
+	"/Podfile.lock",
+	"/Package.resolved",
+	"/go.mod",
+	"/app/package.json",
+	"/client/package.json",
+	"/frontend/package.json",
+	"/static/js/",
+	"/assets/",
+	"/js/",
+	"/dist/",
+	"/build/",
+	"/_next/static/chunks/",
+	"/.well-known/",
+}
+
+var hrefRe = regexp.MustCompile(`(?i)\bhref\s*=\s*["']([^"'#]+)`)
+
+type probeResponse struct {
+	status int
+	size   int
+	html   bool
+}
+
+func probeGET(u string) (probeResponse, []byte, error) {
+	body, status, header, err := httpGETHeader(u, targetHeaders())
+	if err != nil {
+		return probeResponse{}, nil, err
+	}
+	return probeResponse{status: status, size: len(body), html: isHTML(header.Get("Content-Type"), body)}, body, nil
+}
+
+// softNotFound reports whether r looks like the answer the server gives for
+// paths that do not exist.
+func (r probeResponse) softNotFound(baseline probeResponse) bool {
+	if r.status != http.StatusOK {
+		return true
+	}
+	if baseline.status != http.StatusOK || r.html != baseline.html {
+		return false
+	}
+	diff := r.size - baseline.size
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= baseline.size/10+16
+}
+
+func normalizeBase(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	return strings.TrimRight(raw, "/")
+}
+
+// probeBase requests the built-in manifest paths under base and returns the
+// URLs that exist, plus code files listed by exposed directory indexes.
+func probeBase(base string) []string {
+	nonce := fmt.Sprintf("dchero-%08x", rand.Uint32())
+	fileBaseline, _, err := probeGET(base + "/" + nonce + ".json")
+	if err != nil {
+		return nil
+	}
+	dirBaseline, _, err := probeGET(base + "/" + nonce + "/")
+	if err != nil {
+		dirBaseline = fileBaseline
+	}
+
+	var found []string
+	if opts.html {
+		found = append(found, base+"/")
+	}
+	for _, p := range probePaths {
+		u := base + p
+		resp, body, err := probeGET(u)
+		if err != nil {
+			continue
+		}
+		if !strings.HasSuffix(p, "/") {
+			if resp.html || resp.softNotFound(fileBaseline) {
+				continue
+			}
+			found = append(found, u)
+			continue
+		}
+		if !resp.html || resp.softNotFound(dirBaseline) {
+			continue
+		}
+		dir, err := url.Parse(u)
+		if err != nil {
+			continue
+		}
+		for _, m := range hrefRe.FindAllSubmatch(body, -1) {
+			ref, err := url.Parse(strings.TrimSpace(string(m[1])))
+			if err != nil || looksLikePage(ref.Path) {
+				continue
+			}
+			found = append(found, dir.ResolveReference(ref).String())
+		}
+	}
+	return filterManifestURLs(found)
+}
+
+// splitProbeInputs keeps input lines that already are manifest or bundle
+// URLs as they are, so -probe on an ordinary target list still scans it, and
+// returns the rest as bases to probe.
+func splitProbeInputs(urls []string) (manifests, bases []string) {
+	manifests = filterManifestURLs(urls)
+	direct := toSet(manifests)
+	for _, u := range urls {
+		if _, ok := direct[strings.TrimSpace(u)]; !ok {
+			bases = append(bases, u)
+		}
+	}
+	return manifests, bases
+}
+
+func probeTargets(bases []string, threads int) []string {
+	type inp struct{ base string }
+	worker := func(x inp) ([]string, error) {
+		return probeBase(normalizeBase(x.base)), nil
+	}
+	inputs := make([]inp, 0, len(bases))
+	for _, b := range bases {
+		if strings.TrimSpace(b) != "" {
+			inputs = append(inputs, inp{base: b})
+		}
+	}
+	results, _ := runWorkers(inputs, worker, threads)
+	var out []string
+	for _, r := range results {
+		out = append(out, r...)
+	}
+	return out
+}
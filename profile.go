@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// profiles are -profile presets: flag values applied unless the flag is
+// given explicitly.
+var profiles = map[string]map[string]string{
+	// registry HEAD checks only, as many at once as allowed
+	"fast": {"t": "100", "meta": "false", "transitive": "false", "probe": "false", "html": "false", "repo-check": "false", "scope-check": "false"},
+	// registry JSON APIs, probing, HTML pages and their bundles, transitive npm dependencies
+	"thorough": {"meta": "true", "repo-check": "true", "scope-check": "true", "transitive": "true", "depth": "2", "probe": "true", "html": "true"},
+	// a trickle of requests per target host under one User-Agent
+	"stealth": {"t": "4", "per-host": "1", "delay": "2s"},
+}
+
+func profileNames() string {
+	names := make([]string, 0, len(profiles))
+	for n := range profiles {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func applyProfile(name string) error {
+	preset, ok := profiles[name]
+	if !ok {
+		return fmt.Errorf("unknown profile %q (available: %s)", name, profileNames())
+	}
+	explicit := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	for k, v := range preset {
+		if explicit[k] {
+			continue
+		}
+		if err := flag.Set(k, v); err != nil {
+			return err
+		}
+	}
+	if name == "stealth" {
+		// rotating User-Agents from one address stands out more than a fixed one
+		userAgents = []string{randomUA()}
+	}
+	return nil
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	pyFromRe   = regexp.MustCompile(`^from\s+([A-Za-z_][\w.]*)\s+import\b`)
+	pyModuleRe = regexp.MustCompile(`^[A-Za-z_][\w.]*$`)
+	// google.cloud.pubsub_v1 ships in google-cloud-pubsub
+	pyAPIVersionRe = regexp.MustCompile(`_v\d.*$`)
+
+	// sys.stdlib_module_names, plus Python 2 modules old code still imports
+	pyStdlib = toSet(strings.Fields(`
+		abc aifc antigravity argparse array ast asynchat asyncio asyncore atexit audioop base64 bdb binascii bisect
+		builtins bz2 cProfile calendar cgi cgitb chunk cmath cmd code codecs codeop collections colorsys compileall
+		concurrent configparser contextlib contextvars copy copyreg crypt csv ctypes curses dataclasses datetime dbm
+		decimal difflib dis distutils doctest email encodings ensurepip enum errno faulthandler fcntl filecmp fileinput
+		fnmatch fractions ftplib functools gc genericpath getopt getpass gettext glob graphlib grp gzip hashlib heapq
+		hmac html http idlelib imaplib imghdr imp importlib inspect io ipaddress itertools json keyword lib2to3
+		linecache locale logging lzma mailbox mailcap marshal math mimetypes mmap modulefinder msilib msvcrt
+		multiprocessing netrc nis nntplib nt ntpath nturl2path numbers opcode operator optparse os ossaudiodev pathlib
+		pdb pickle pickletools pipes pkgutil platform plistlib poplib posix posixpath pprint profile pstats pty pwd
+		py_compile pyclbr pydoc pydoc_data pyexpat queue quopri random re readline reprlib resource rlcompleter runpy
+		sched secrets select selectors shelve shlex shutil signal site smtpd smtplib sndhdr socket socketserver spwd
+		sqlite3 sre_compile sre_constants sre_parse ssl stat statistics string stringprep struct subprocess sunau
+		symtable sys sysconfig syslog tabnanny tarfile telnetlib tempfile termios textwrap this threading time timeit
+		tkinter token tokenize tomllib trace traceback tracemalloc tty turtle turtledemo types typing unicodedata
+		unittest urllib uu uuid venv warnings wave weakref webbrowser winreg winsound wsgiref xdrlib xml xmlrpc
+		zipapp zipfile zipimport zlib zoneinfo
+		BaseHTTPServer ConfigParser Cookie HTMLParser Queue SimpleHTTPServer SocketServer StringIO Tkinter anydbm
+		commands cPickle cStringIO cookielib copy_reg dummy_thread exceptions httplib md5 repr sets sha thread
+		urllib2 urlparse whichdb xmlrpclib`))
+
+	pyNamespaces = toSet([]string{"google", "google.cloud", "azure"})
+
+	// whether a directory of a target holds a module or package, and what
+	// the directory answers for files that don't exist
+	pyModuleLookups   = newLookups[bool]("python module")
+	pyBaselineLookups = newLookups[probeResponse]("python baseline")
+
+	// import names that differ from the distribution that provides them
+	pyImportDists = map[string]string{
+		"cv2":             "opencv-python",
+		"PIL":             "Pillow",
+		"yaml":            "PyYAML",
+		"sklearn":         "scikit-learn",
+		"skimage":         "scikit-image",
+		"bs4":             "beautifulsoup4",
+		"dateutil":        "python-dateutil",
+		"dotenv":          "python-dotenv",
+		"jwt":             "PyJWT",
+		"jose":            "python-jose",
+		"Crypto":          "pycryptodome",
+		"Cryptodome":      "pycryptodomex",
+		"OpenSSL":         "pyOpenSSL",
+		"nacl":            "PyNaCl",
+		"magic":           "python-magic",
+		"serial":          "pyserial",
+		"usb":             "pyusb",
+		"socks":           "PySocks",
+		"slugify":         "python-slugify",
+		"multipart":       "python-multipart",
+		"ldap":            "python-ldap",
+		"git":             "GitPython",
+		"github":          "PyGithub",
+		"gitlab":          "python-gitlab",
+		"kafka":           "kafka-python",
+		"zmq":             "pyzmq",
+		"MySQLdb":         "mysqlclient",
+		"gi":              "PyGObject",
+		"attr":            "attrs",
+		"docx":            "python-docx",
+		"pptx":            "python-pptx",
+		"fitz":            "PyMuPDF",
+		"Levenshtein":     "python-Levenshtein",
+		"telegram":        "python-telegram-bot",
+		"discord":         "discord.py",
+		"Xlib":            "python-xlib",
+		"mpl_toolkits":    "matplotlib",
+		"pkg_resources":   "setuptools",
+		"win32api":        "pywin32",
+		"win32con":        "pywin32",
+		"win32com":        "pywin32",
+		"pythoncom":       "pywin32",
+		"pywintypes":      "pywin32",
+		"dns":             "dnspython",
+		"engineio":        "python-engineio",
+		"socketio":        "python-socketio",
+		"jenkins":         "python-jenkins",
+		"keystoneclient":  "python-keystoneclient",
+		"memcache":        "python-memcached",
+		"snappy":          "python-snappy",
+		"stdnum":          "python-stdnum",
+		"websocket":       "websocket-client",
+		"wx":              "wxPython",
+		"google.protobuf": "protobuf",
+		"google.oauth2":   "google-auth",
+		"googleapiclient": "google-api-python-client",
+		"azure.storage":   "azure-storage-blob",
+		"ruamel":          "ruamel.yaml",
+		"OpenGL":          "PyOpenGL",
+		"Bio":             "biopython",
+	}
+)
+
+func toSet(names []string) map[string]struct{} {
+	m := make(map[string]struct{}, len(names))
+	for _, n := range names {
+		m[n] = struct{}{}
+	}
+	return m
+}
+
+// isPythonSource reports .py files other than setup.py, which is a manifest.
+func isPythonSource(p string) bool {
+	l := strings.ToLower(p)
+	return strings.HasSuffix(l, ".py") && path.Base(l) != "setup.py"
+}
+
+// pythonDistribution returns the PyPI distribution an absolute import
+// installs from, or false for the standard library and private modules.
+func pythonDistribution(module string) (string, bool) {
+	parts := strings.Split(module, ".")
+	top := parts[0]
+	if _, ok := pyStdlib[top]; ok || strings.HasPrefix(top, "_") {
+		return "", false
+	}
+	if len(parts) > 1 {
+		if d, ok := pyImportDists[top+"."+parts[1]]; ok {
+			return d, true
+		}
+		// namespace packages ship one distribution per sub-package
+		switch {
+		case top == "google" && parts[1] == "cloud" && len(parts) > 2:
+			return "google-cloud-" + pyAPIVersionRe.ReplaceAllString(parts[2], ""), true
+		case top == "google" || top == "azure":
+			return top + "-" + parts[1], true
+		}
+	}
+	if d, ok := pyImportDists[top]; ok {
+		return d, true
+	}
+	return top, true
+}
+
+// projectModule reports whether top names a module of the project fileURL
+// belongs to rather than a distribution: a directory on the file's own path,
+// or a top.py or top/__init__.py next to it. Projects import their own
+// packages absolutely as often as relatively.
+func projectModule(fileURL, top string) bool {
+	u, err := url.Parse(fileURL)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	dir := path.Dir(u.Path)
+	for _, seg := range strings.Split(dir, "/") {
+		if seg == top {
+			return true
+		}
+	}
+	u.RawQuery, u.Fragment = "", ""
+	u.Path = strings.TrimSuffix(dir, "/") + "/"
+	base := u.String()
+	return pyModuleLookups.do(base+top, func() (bool, int) {
+		baseline := pyBaselineLookups.do(base, func() (probeResponse, int) {
+			r, _, _ := probeGET(base + fmt.Sprintf("dchero-%08x.py", rand.Uint32()))
+			return r, 0
+		})
+		for _, p := range []string{top + ".py", top + "/__init__.py"} {
+			r, _, err := probeGET(base + p)
+			if err == nil && !r.html && !r.softNotFound(baseline) {
+				return true, 0
+			}
+		}
+		return false, 0
+	})
+}
+
+// parsePythonImports returns the distributions behind the import statements
+// of a Python file. Relative imports, the standard library and the project's
+// own modules are skipped; the rest are code references, not declared
+// dependencies.
+func parsePythonImports(targetURL string, body []byte) []dependency {
+	set := make(map[string]struct{})
+	add := func(module string) {
+		if !pyModuleRe.MatchString(module) {
+			return
+		}
+		d, ok := pythonDistribution(module)
+		if !ok {
+			return
+		}
+		// a mapped import name is a known public distribution
+		if top, _, _ := strings.Cut(module, "."); d == top && projectModule(targetURL, top) {
+			return
+		}
+		set[d] = struct{}{}
+	}
+	// the triple quote of the docstring we are in, if any
+	var quote string
+	for _, line := range strings.Split(string(body), "\n") {
+		if quote != "" {
+			if strings.Count(line, quote)%2 == 1 {
+				quote = ""
+			}
+			continue
+		}
+		code := line
+		if i := strings.IndexByte(code, '#'); i >= 0 {
+			code = code[:i]
+		}
+		for _, q := range []string{`"""`, `'''`} {
+			if strings.Count(code, q)%2 == 1 {
+				quote, code = q, code[:strings.Index(code, q)]
+				break
+			}
+		}
+		for _, stmt := range strings.Split(code, ";") {
+			stmt = strings.TrimSpace(stmt)
+			if m := pyFromRe.FindStringSubmatch(stmt); m != nil {
+				if _, ok := pyNamespaces[m[1]]; !ok {
+					add(m[1])
+					continue
+				}
+				// from google.cloud import storage installs google-cloud-storage
+				for _, part := range strings.Split(strings.Trim(stmt[len(m[0]):], " ()\\"), ",") {
+					if f := strings.Fields(part); len(f) > 0 {
+						add(m[1] + "." + f[0])
+					}
+				}
+				continue
+			}
+			if rest, ok := strings.CutPrefix(stmt, "import "); ok {
+				for _, part := range strings.Split(rest, ",") {
+					if f := strings.Fields(part); len(f) > 0 {
+						add(f[0])
+					}
+				}
+			}
+		}
+	}
+	names := make([]string, 0, len(set))
+	for n := range set {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	deps := namesToDeps(names)
+	for i := range deps {
+		deps[i].Type = depCode
+	}
+	return deps
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	setupKeywordRe = regexp.MustCompile(`\b(install_requires|setup_requires|tests_require|extras_require)\s*=\s*`)
+	pyStringRe     = regexp.MustCompile(`'([^'\\]*)'|"([^"\\]*)"`)
+	pyIdentRe      = regexp.MustCompile(`^[A-Za-z_]\w*`)
+)
+
+func isSetupManifest(p string) bool {
+	switch strings.ToLower(path.Base(p)) {
+	case "setup.py", "setup.cfg":
+		return true
+	}
+	return false
+}
+
+func parseSetupManifest(name string, body []byte) []dependency {
+	if strings.EqualFold(path.Base(name), "setup.cfg") {
+		return parseSetupCfg(string(body))
+	}
+	return parseSetupPy(string(body))
+}
+
+// parseSetupPy reads the requirement lists passed to setup(). Lists given as
+// a variable are looked up in the module; lists built at runtime (read from
+// requirements.txt and the like) are not followed.
+func parseSetupPy(src string) []dependency {
+	var deps []dependency
+	for _, m := range setupKeywordRe.FindAllStringSubmatchIndex(src, -1) {
+		typ := setupKeywordType(src[m[2]:m[3]])
+		value := src[m[1]:]
+		if id := pyIdentRe.FindString(value); id != "" {
+			value = pyAssignment(src, id)
+		}
+		lit := pyBracketed(value)
+		for _, s := range pyStringRe.FindAllStringSubmatchIndex(lit, -1) {
+			// extras_require keys are followed by a colon
+			if strings.HasPrefix(strings.TrimSpace(lit[s[1]:]), ":") {
+				continue
+			}
+			var req string
+			if s[2] >= 0 {
+				req = lit[s[2]:s[3]]
+			} else {
+				req = lit[s[4]:s[5]]
+			}
+			if d, ok := requirementDep(strings.TrimSpace(req)); ok {
+				d.Type = typ
+				deps = append(deps, d)
+			}
+		}
+	}
+	return deps
+}
+
+// setupKeywordType tells build and test requirements from what gets
+// installed with the package.
+func setupKeywordType(key string) depType {
+	if key == "setup_requires" || key == "tests_require" {
+		return depDev
+	}
+	return ""
+}
+
+// pyAssignment returns the source following the last "name = " at the start
+// of a line, or "" if there is none.
+func pyAssignment(src, name string) string {
+	re := regexp.MustCompile(`(?m)^\s*` + regexp.QuoteMeta(name) + `\s*=\s*`)
+	locs := re.FindAllStringIndex(src, -1)
+	if len(locs) == 0 {
+		return ""
+	}
+	return src[locs[len(locs)-1][1]:]
+}
+
+// pyBracketed returns the list, tuple or dict literal src starts with, up to
+// its matching bracket. Brackets inside strings and comments are skipped.
+func pyBracketed(src string) string {
+	if src == "" || !strings.ContainsRune("[({", rune(src[0])) {
+		return ""
+	}
+	depth := 0
+	var quote byte
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case c == '[' || c == '(' || c == '{':
+			depth++
+		case c == ']' || c == ')' || c == '}':
+			depth--
+			if depth == 0 {
+				return src[:i+1]
+			}
+		}
+	}
+	return src
+}
+
+// parseSetupCfg reads install_requires, setup_requires and tests_require of
+// [options] and every list of [options.extras_require]. Values are either on
+// the key's line or on the indented lines below it.
+func parseSetupCfg(src string) []dependency {
+	var deps []dependency
+	var section string
+	var typ depType
+	collecting := false
+	for _, ln := range strings.Split(src, "\n") {
+		line := strings.TrimSpace(ln)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.ToLower(strings.Trim(line, "[] "))
+			collecting = false
+			continue
+		}
+		value := line
+		if ln[0] != ' ' && ln[0] != '\t' {
+			key, v, ok := strings.Cut(line, "=")
+			if !ok {
+				collecting = false
+				continue
+			}
+			key = strings.TrimSpace(key)
+			typ = setupKeywordType(key)
+			switch {
+			case section == "options.extras_require":
+				collecting = true
+			case section == "options" && (key == "install_requires" || key == "setup_requires" || key == "tests_require"):
+				collecting = true
+			default:
+				collecting = false
+			}
+			value = strings.TrimSpace(v)
+		}
+		if !collecting || value == "" {
+			continue
+		}
+		// file: and attr: directives point elsewhere
+		if strings.HasPrefix(value, "file:") || strings.HasPrefix(value, "attr:") {
+			continue
+		}
+		if i := strings.Index(value, " #"); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		}
+		for _, req := range splitRequirements(value) {
+			if d, ok := requirementDep(strings.TrimSpace(req)); ok {
+				d.Type = typ
+				deps = append(deps, d)
+			}
+		}
+	}
+	return deps
+}
+
+// splitRequirements splits "a>=1,<2, b[x,y]" into its requirements: commas
+// inside extras and between version clauses don't start a new one.
+func splitRequirements(s string) []string {
+	var out []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case ',':
+			next := strings.TrimSpace(s[i+1:])
+			if depth == 0 && next != "" && pyIdentRe.MatchString(next) {
+				out = append(out, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(out, s[start:])
+}
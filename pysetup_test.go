@@ -0,0 +1,178 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSetupPy(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []dependency
+	}{
+		{
+			name: "inline lists",
+			src: `from setuptools import setup, find_packages
+
+setup(
+    name="acme-service",
+    version="1.2.0",
+    packages=find_packages(),
+    install_requires=[
+        "requests>=2.28,<3",  # [pinned] for the http client
+        'acme-core==1.4.0',
+        "pywin32>=300; sys_platform == 'win32'",
+        "acme-auth @ git+https://git.acme.corp/py/acme-auth.git",
+    ],
+    setup_requires=("setuptools_scm",),
+    tests_require=["pytest"],
+    extras_require={
+        "s3": ["boto3>=1.26"],
+        "dev": ["black", "acme-lint"],
+    },
+)
+`,
+			want: []dependency{
+				{Name: "requests", Spec: ">=2.28,<3"},
+				{Name: "acme-core", Spec: "==1.4.0"},
+				{Name: "pywin32", Spec: ">=300"},
+				{Name: "setuptools_scm", Type: depDev},
+				{Name: "pytest", Type: depDev},
+				{Name: "boto3", Spec: ">=1.26"},
+				{Name: "black"},
+				{Name: "acme-lint"},
+			},
+		},
+		{
+			name: "list held in a variable",
+			src: `REQUIRES = ["click"]
+REQUIRES = [
+    "click>=8",
+    "acme-cli-utils",
+]
+
+setup(name="tool", install_requires=REQUIRES)
+`,
+			want: []dependency{
+				{Name: "click", Spec: ">=8"},
+				{Name: "acme-cli-utils"},
+			},
+		},
+		{
+			name: "list built at runtime",
+			src: `with open("requirements.txt") as f:
+    reqs = f.read().splitlines()
+
+setup(name="tool", install_requires=reqs)
+`,
+		},
+		{
+			name: "no setup call",
+			src:  "print('hello')\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseSetupPy(tt.src); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSetupCfg(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []dependency
+	}{
+		{
+			name: "options and extras",
+			src: `[metadata]
+name = acme-service
+install_requires = not-a-dependency
+
+[options]
+packages = find:
+python_requires = >=3.8
+install_requires =
+    requests>=2.28
+    acme-core==1.4.0  # internal
+    acme-auth @ git+https://git.acme.corp/py/acme-auth.git
+setup_requires = setuptools_scm>=6
+tests_require =
+    pytest
+
+[options.extras_require]
+s3 = boto3>=1.26, acme-s3[fast,async]>=2
+dev =
+    black
+
+[flake8]
+max-line-length = 100
+`,
+			want: []dependency{
+				{Name: "requests", Spec: ">=2.28"},
+				{Name: "acme-core", Spec: "==1.4.0"},
+				{Name: "setuptools_scm", Spec: ">=6", Type: depDev},
+				{Name: "pytest", Type: depDev},
+				{Name: "boto3", Spec: ">=1.26"},
+				{Name: "acme-s3", Spec: ">=2"},
+				{Name: "black"},
+			},
+		},
+		{
+			name: "directives and CRLF",
+			src:  "[options]\r\ninstall_requires = file: requirements.txt\r\ntests_require =\r\n    pytest>=7\r\n",
+			want: []dependency{{Name: "pytest", Spec: ">=7", Type: depDev}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseSetupCfg(tt.src); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitRequirements(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"requests", []string{"requests"}},
+		{"a>=1,<2, b", []string{"a>=1,<2", " b"}},
+		{"x[y,z]>=1,w", []string{"x[y,z]>=1", "w"}},
+		{"a>=1, !=1.5", []string{"a>=1, !=1.5"}},
+	}
+	for _, tt := range tests {
+		if got := splitRequirements(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitRequirements(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRequirementDep(t *testing.T) {
+	tests := []struct {
+		in   string
+		want dependency
+		ok   bool
+	}{
+		{"requests", dependency{Name: "requests"}, true},
+		{"requests>=2.28,<3", dependency{Name: "requests", Spec: ">=2.28,<3"}, true},
+		{"requests[security,socks] >= 2.28", dependency{Name: "requests", Spec: ">= 2.28"}, true},
+		{"pywin32>=300; sys_platform == 'win32'", dependency{Name: "pywin32", Spec: ">=300"}, true},
+		{"acme-core ~= 1.4", dependency{Name: "acme-core", Spec: "~= 1.4"}, true},
+		{"acme-auth @ git+https://git.acme.corp/py/acme-auth.git", dependency{}, false},
+		{"acme-auth[cli] @ https://files.acme.corp/acme_auth-1.0.whl", dependency{}, false},
+		{">=1.0", dependency{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := requirementDep(tt.in)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("requirementDep(%q) = %+v, %v, want %+v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redisClient speaks just enough RESP for a shared cache: AUTH, SELECT,
+// PING, GET and SET with an expiry.
+type redisClient struct {
+	addr     string
+	tls      bool
+	user     string
+	password string
+	db       int
+
+	conns chan *redisConn
+}
+
+type redisConn struct {
+	c net.Conn
+	r *bufio.Reader
+}
+
+func newRedisClient(raw string) (*redisClient, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return nil, fmt.Errorf("unsupported cache %q, expected redis:// or rediss://", raw)
+	}
+	c := &redisClient{addr: u.Host, tls: u.Scheme == "rediss", conns: make(chan *redisConn, 100)}
+	if u.Port() == "" {
+		c.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		c.user = u.User.Username()
+		c.password, _ = u.User.Password()
+		// redis://:password@host carries no user name
+		if _, ok := u.User.Password(); !ok {
+			c.password, c.user = c.user, ""
+		}
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if c.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid redis database %q", db)
+		}
+	}
+	return c, nil
+}
+
+func (c *redisClient) dial() (*redisConn, error) {
+	d := &net.Dialer{Timeout: 5 * time.Second}
+	var conn net.Conn
+	var err error
+	if c.tls {
+		conn, err = tls.DialWithDialer(d, "tcp", c.addr, &tls.Config{})
+	} else {
+		conn, err = d.Dial("tcp", c.addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	rc := &redisConn{c: conn, r: bufio.NewReader(conn)}
+	var setup [][]string
+	switch {
+	case c.password != "" && c.user != "":
+		setup = append(setup, []string{"AUTH", c.user, c.password})
+	case c.password != "":
+		setup = append(setup, []string{"AUTH", c.password})
+	}
+	if c.db != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(c.db)})
+	}
+	for _, args := range setup {
+		if _, err := rc.do(args...); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return rc, nil
+}
+
+func (c *redisClient) do(args ...string) (any, error) {
+	var rc *redisConn
+	select {
+	case rc = <-c.conns:
+	default:
+		var err error
+		if rc, err = c.dial(); err != nil {
+			return nil, err
+		}
+	}
+	v, err := rc.do(args...)
+	var re redisError
+	if err != nil && !errors.As(err, &re) {
+		// the connection state is unknown after an I/O error
+		rc.c.Close()
+		return nil, err
+	}
+	select {
+	case c.conns <- rc:
+	default:
+		rc.c.Close()
+	}
+	return v, err
+}
+
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+func (rc *redisConn) do(args ...string) (any, error) {
+	rc.c.SetDeadline(time.Now().Add(5 * time.Second))
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := io.WriteString(rc.c, b.String()); err != nil {
+		return nil, err
+	}
+	return readRESP(rc.r)
+}
+
+// readRESP reads one reply: nil for null bulk strings and arrays.
+func readRESP(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		out := make([]any, n)
+		for i := range out {
+			if out[i], err = readRESP(r); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply %q", line)
+}
+
+// redisPackageStore shares claimed/unclaimed answers between processes.
+type redisPackageStore struct {
+	c    *redisClient
+	ttl  time.Duration
+	warn sync.Once
+}
+
+func (s *redisPackageStore) failed(err error) {
+	s.warn.Do(func() { fmt.Fprintf(os.Stderr, "redis cache: %v, continuing with the local cache\n", err) })
+}
+
+func (s *redisPackageStore) load(key string) (packageStatus, bool) {
+	v, err := s.c.do("GET", "dchero:package:"+key)
+	if err != nil {
+		s.failed(err)
+		return packageStatus{}, false
+	}
+	str, ok := v.(string)
+	if !ok {
+		return packageStatus{}, false
+	}
+	status, err := strconv.Atoi(str)
+	if err != nil {
+		return packageStatus{}, false
+	}
+	return sharedPackageStatus(status), true
+}
+
+func (s *redisPackageStore) store(key string, v packageStatus) {
+	if !shareable(v) {
+		return
+	}
+	if _, err := s.c.do("SET", "dchero:package:"+key, strconv.Itoa(v.status), "EX", strconv.Itoa(int(s.ttl.Seconds()))); err != nil {
+		s.failed(err)
+	}
+}
+
+// setupSharedCache points the package lookups at the -cache backend.
+func setupSharedCache(raw string, ttl time.Duration) error {
+	c, err := newRedisClient(raw)
+	if err != nil {
+		return err
+	}
+	if _, err := c.do("PING"); err != nil {
+		return err
+	}
+	if ttl < time.Second {
+		ttl = time.Second
+	}
+	packageLookups.shared = &redisPackageStore{c: c, ttl: ttl}
+	return nil
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type npmPerson struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type npmPackument struct {
+	Name        string                     `json:"name"`
+	Description string                     `json:"description"`
+	DistTags    map[string]string          `json:"dist-tags"`
+	Maintainers []npmPerson                `json:"maintainers"`
+	Versions    map[string]json.RawMessage `json:"versions"`
+	Time        map[string]json.RawMessage `json:"time"`
+	Repository  json.RawMessage            `json:"repository"`
+}
+
+func (m *npmPackument) unpublished() (time.Time, bool) {
+	raw, ok := m.Time["unpublished"]
+	if !ok {
+		return time.Time{}, false
+	}
+	var u struct {
+		Time time.Time `json:"time"`
+	}
+	if err := json.Unmarshal(raw, &u); err != nil {
+		return time.Time{}, true
+	}
+	return u.Time, true
+}
+
+func fetchNPMMeta(pkg string) (*npmPackument, error) {
+	r := npmMetaLookups.do(pkg, func() (metaResult[npmPackument], int) {
+		m, size, err := getNPMMeta(pkg)
+		return metaResult[npmPackument]{m, err}, size
+	})
+	return r.m, r.err
+}
+
+func getNPMMeta(pkg string) (*npmPackument, int, error) {
+	h := map[string]string{"User-Agent": randomUA(), "Accept": "application/json"}
+	body, status, err := httpGET(registryURL(pkg, langJS), h)
+	if err != nil {
+		return nil, 0, err
+	}
+	var m npmPackument
+	if err := json.Unmarshal(body, &m); err != nil && status == http.StatusOK {
+		return nil, 0, err
+	}
+	// unpublished packuments may come back with an error status
+	if _, removed := m.unpublished(); status != http.StatusOK && !removed {
+		return nil, 0, fmt.Errorf("npm metadata for %s returned %d", pkg, status)
+	}
+	return &m, len(body), nil
+}
+
+// npmMetaKind reports names that answer but are not usable packages: names
+// removed by their owner, names npm took over after a malware removal, and
+// stubs without versions.
+func npmMetaKind(pkg string) (findingKind, string) {
+	m, err := fetchNPMMeta(pkg)
+	if err != nil {
+		return "", ""
+	}
+	if at, ok := m.unpublished(); ok {
+		return kindUnpublished, removedDetail(at)
+	}
+	for _, p := range m.Maintainers {
+		if strings.EqualFold(p.Email, "npm@npmjs.com") || strings.EqualFold(p.Name, "npm") {
+			return kindSecurityHolder, ""
+		}
+	}
+	if strings.HasSuffix(m.DistTags["latest"], "-security") || strings.EqualFold(m.Description, "security holding package") {
+		return kindSecurityHolder, ""
+	}
+	if len(m.Versions) == 0 {
+		return kindPlaceholder, ""
+	}
+	return "", ""
+}
+
+func removedDetail(at time.Time) string {
+	if at.IsZero() {
+		return "removed at an unknown date"
+	}
+	days := int(time.Since(at).Hours() / 24)
+	return fmt.Sprintf("removed %s, %d days ago", at.UTC().Format("2006-01-02"), days)
+}
+
+type pypiFile struct {
+	UploadTime time.Time `json:"upload_time_iso_8601"`
+	Yanked     bool      `json:"yanked"`
+}
+
+type pypiProject struct {
+	Info struct {
+		Name        string            `json:"name"`
+		Version     string            `json:"version"`
+		HomePage    string            `json:"home_page"`
+		ProjectURLs map[string]string `json:"project_urls"`
+	} `json:"info"`
+	Releases map[string][]pypiFile `json:"releases"`
+}
+
+func fetchPyPIMeta(pkg string) (*pypiProject, error) {
+	r := pypiMetaLookups.do(pkg, func() (metaResult[pypiProject], int) {
+		m, size, err := getPyPIMeta(pkg)
+		return metaResult[pypiProject]{m, err}, size
+	})
+	return r.m, r.err
+}
+
+func getPyPIMeta(pkg string) (*pypiProject, int, error) {
+	h := map[string]string{"User-Agent": randomUA(), "Accept": "application/json"}
+	body, status, err := httpGET(fmt.Sprintf(pypiJSONURL, pkg), h)
+	if err != nil {
+		return nil, 0, err
+	}
+	if status != http.StatusOK {
+		return nil, 0, fmt.Errorf("pypi metadata for %s returned %d", pkg, status)
+	}
+	var m pypiProject
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, 0, err
+	}
+	return &m, len(body), nil
+}
+
+// pypiMetaKind reports projects that still exist but have nothing left to
+// install: every release file was deleted or yanked.
+func pypiMetaKind(pkg string) (findingKind, string) {
+	m, err := fetchPyPIMeta(pkg)
+	if err != nil {
+		return "", ""
+	}
+	var last time.Time
+	for _, files := range m.Releases {
+		for _, f := range files {
+			if !f.Yanked {
+				return "", ""
+			}
+			if f.UploadTime.After(last) {
+				last = f.UploadTime
+			}
+		}
+	}
+	if last.IsZero() {
+		return kindUnpublished, "no release files left"
+	}
+	return kindUnpublished, fmt.Sprintf("all releases yanked, last upload %s", last.UTC().Format("2006-01-02"))
+}
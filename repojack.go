@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+var githubWebURL = "https://github.com"
+
+// githubRepo extracts owner and repository from the URL forms package
+// metadata uses: https and git URLs, scp-style git@github.com:owner/repo and
+// npm's "github:owner/repo" / "owner/repo" shorthands.
+func githubRepo(raw string) (owner, repo string, ok bool) {
+	s := strings.TrimSpace(raw)
+	switch {
+	case strings.HasPrefix(s, "github:"):
+		s = strings.TrimPrefix(s, "github:")
+	case strings.HasPrefix(s, "git@github.com:"):
+		s = strings.TrimPrefix(s, "git@github.com:")
+	case strings.Contains(s, "://"):
+		u, err := url.Parse(strings.TrimPrefix(s, "git+"))
+		if err != nil || !strings.EqualFold(strings.TrimPrefix(u.Hostname(), "www."), "github.com") {
+			return "", "", false
+		}
+		s = strings.TrimPrefix(u.Path, "/")
+	case strings.Count(s, "/") != 1 || strings.Contains(s, ":"):
+		return "", "", false
+	}
+	parts := strings.Split(s, "/")
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	owner, repo = parts[0], strings.TrimSuffix(parts[1], ".git")
+	// github.com/sponsors/..., /orgs/... and the like are not owners
+	switch strings.ToLower(owner) {
+	case "sponsors", "orgs", "users", "features", "topics", "marketplace", "apps":
+		return "", "", false
+	}
+	return owner, repo, true
+}
+
+func npmRepositoryURLs(m *npmPackument) []string {
+	var s string
+	if json.Unmarshal(m.Repository, &s) == nil {
+		return []string{s}
+	}
+	var obj struct {
+		URL string `json:"url"`
+	}
+	if json.Unmarshal(m.Repository, &obj) == nil && obj.URL != "" {
+		return []string{obj.URL}
+	}
+	return nil
+}
+
+func pypiRepositoryURLs(m *pypiProject) []string {
+	urls := []string{m.Info.HomePage}
+	for _, u := range m.Info.ProjectURLs {
+		urls = append(urls, u)
+	}
+	return urls
+}
+
+// repojackKind reports claimed packages whose source repository lives under a
+// GitHub account that no longer exists: anyone can register the name and
+// serve code from the URL the package points to.
+func repojackKind(pkg string, lang language) (findingKind, string) {
+	var urls []string
+	switch lang {
+	case langJS:
+		m, err := fetchNPMMeta(pkg)
+		if err != nil {
+			return "", ""
+		}
+		urls = npmRepositoryURLs(m)
+	case langPython:
+		m, err := fetchPyPIMeta(pkg)
+		if err != nil {
+			return "", ""
+		}
+		urls = pypiRepositoryURLs(m)
+	}
+
+	seen := make(map[string]struct{})
+	var dead []string
+	for _, u := range urls {
+		owner, repo, ok := githubRepo(u)
+		if !ok {
+			continue
+		}
+		key := strings.ToLower(owner)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		status, err := httpHEAD(githubWebURL+"/"+url.PathEscape(owner), map[string]string{"User-Agent": randomUA()})
+		if err == nil && status == http.StatusNotFound {
+			dead = append(dead, fmt.Sprintf("github.com/%s/%s", owner, repo))
+		}
+	}
+	if len(dead) == 0 {
+		return "", ""
+	}
+	sort.Strings(dead)
+	return kindRepojack, strings.Join(dead, ", ")
+}
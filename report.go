@@ -0,0 +1,215 @@
+package main
+
+import (
+	htmltemplate "html/template"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+type reportFinding struct {
+	Summary     string
+	Package     string
+	Language    language
+	Kind        findingKind
+	Type        depType
+	Status      int
+	URL         string
+	Snippet     string
+	Remediation string
+}
+
+type reportLevel struct {
+	Confidence confidence
+	Findings   []reportFinding
+}
+
+type reportGroup struct {
+	Domain string
+	Levels []reportLevel
+}
+
+type reportData struct {
+	Generated string
+	Total     int
+	Groups    []reportGroup
+}
+
+var remediation = map[language]string{
+	langJS: "Register the name on npm (or claim its scope as an organization) as an empty placeholder, " +
+		"and route internal packages to the private registry with a scoped .npmrc entry (@scope:registry=...). " +
+		"Prefer scoped names for internal packages and commit lockfiles with integrity hashes.",
+	langPython: "Register the name on PyPI as a placeholder and install internal packages with --index-url " +
+		"pointing at the private index instead of --extra-index-url, which lets the public index win. " +
+		"Pin versions with hashes (--require-hashes).",
+	langCocoaPods: "Register the pod name on CocoaPods trunk (pod trunk push of a placeholder podspec) and declare the private " +
+		"spec repo with an explicit source line before the CDN in every Podfile, or pin internal pods with :git.",
+	langSwift: "Point the dependency at a repository you control, or register the GitHub account the URL refers to " +
+		"so nobody else can recreate the repository.",
+	langHelm: "Publish a placeholder chart of that name in a public repository listed on Artifact Hub, reference internal " +
+		"charts by full repository URL instead of an alias, and remove dependencies on repositories that no longer exist.",
+	langVSCode: "Create the publisher on the Visual Studio Marketplace and claim the namespace on Open VSX, or drop the " +
+		"extension from extensionDependencies and extensionPack.",
+	langBrowserExt: "Re-register the update host or bucket, or ship an update that points update_url at a host you control " +
+		"before anyone else claims it.",
+	langR: "Install internal packages from a repository listed before CRAN in options(repos) and renv.lock, or from " +
+		"a pinned remote; register the GitHub account behind any remote whose owner no longer exists.",
+}
+
+var confidenceOrder = map[confidence]int{confHigh: 0, confMedium: 1, confLow: 2}
+
+// severity is the confidence of a finding, one level lower when only dev
+// tooling installs the package.
+func (v vuln) severity() confidence {
+	if v.Type != depDev {
+		return v.Confidence
+	}
+	switch v.Confidence {
+	case confHigh:
+		return confMedium
+	case confMedium:
+		return confLow
+	}
+	return v.Confidence
+}
+
+func buildReport(results []scanResult, now time.Time) reportData {
+	byDomain := make(map[string]map[confidence][]reportFinding)
+	total := 0
+	for _, r := range results {
+		if r.err != nil {
+			continue
+		}
+		domain := r.u
+		if p, err := url.Parse(r.u); err == nil && p.Host != "" {
+			domain = p.Host
+		}
+		for _, v := range r.vulns {
+			if byDomain[domain] == nil {
+				byDomain[domain] = make(map[confidence][]reportFinding)
+			}
+			byDomain[domain][v.Confidence] = append(byDomain[domain][v.Confidence], reportFinding{
+				Summary:     v.summary(),
+				Package:     v.Package,
+				Language:    v.Language,
+				Kind:        v.Kind,
+				Type:        v.Type,
+				Status:      v.Status,
+				URL:         r.u,
+				Snippet:     v.Snippet,
+				Remediation: remediation[v.Language],
+			})
+			total++
+		}
+	}
+
+	data := reportData{Generated: now.UTC().Format(time.RFC1123), Total: total}
+	for domain, levels := range byDomain {
+		g := reportGroup{Domain: domain}
+		for c, ff := range levels {
+			sort.Slice(ff, func(i, j int) bool {
+				if ff[i].Package != ff[j].Package {
+					return ff[i].Package < ff[j].Package
+				}
+				return ff[i].URL < ff[j].URL
+			})
+			g.Levels = append(g.Levels, reportLevel{Confidence: c, Findings: ff})
+		}
+		sort.Slice(g.Levels, func(i, j int) bool {
+			return confidenceOrder[g.Levels[i].Confidence] < confidenceOrder[g.Levels[j].Confidence]
+		})
+		data.Groups = append(data.Groups, g)
+	}
+	sort.Slice(data.Groups, func(i, j int) bool { return data.Groups[i].Domain < data.Groups[j].Domain })
+	return data
+}
+
+const markdownReport = `# DCHero dependency confusion report
+
+Generated {{.Generated}} — {{.Total}} finding(s).
+{{range .Groups}}
+## {{.Domain}}
+{{range .Levels}}
+### {{.Confidence}} confidence
+{{range .Findings}}
+#### ` + "`{{.Package}}`" + ` ({{.Language}}, {{.Kind}}{{with .Type}}, {{.}}{{end}}, HTTP {{.Status}})
+
+{{.Summary}}
+
+- Source: {{.URL}}
+{{- if .Snippet}}
+
+` + "```" + `
+{{.Snippet}}
+` + "```" + `
+{{- end}}
+
+**Remediation:** {{.Remediation}}
+{{end}}{{end}}{{end}}`
+
+const htmlReport = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>DCHero report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em auto; max-width: 1000px; color: #222; }
+h1 { border-bottom: 2px solid #c0392b; padding-bottom: .3em; }
+h2 { margin-top: 2em; color: #c0392b; }
+.finding { border: 1px solid #ddd; border-radius: 4px; padding: .8em 1em; margin: .8em 0; }
+.finding h4 { margin: 0 0 .4em; }
+.meta { color: #666; font-size: .9em; word-break: break-all; }
+pre { background: #f5f5f5; padding: .6em; overflow-x: auto; white-space: pre-wrap; word-break: break-all; }
+.conf-high { color: #c0392b; } .conf-medium { color: #d35400; } .conf-low { color: #7f8c8d; }
+</style>
+</head>
+<body>
+<h1>DCHero dependency confusion report</h1>
+<p>Generated {{.Generated}} — {{.Total}} finding(s).</p>
+{{range .Groups}}
+<h2>{{.Domain}}</h2>
+{{range .Levels}}
+<h3 class="conf-{{.Confidence}}">{{.Confidence}} confidence</h3>
+{{range .Findings}}
+<div class="finding">
+<h4><code>{{.Package}}</code> ({{.Language}}, {{.Kind}}{{with .Type}}, {{.}}{{end}}, HTTP {{.Status}})</h4>
+<p>{{.Summary}}</p>
+<div class="meta">Source: <a href="{{.URL}}">{{.URL}}</a></div>
+{{if .Snippet}}<pre>{{.Snippet}}</pre>{{end}}
+<p><strong>Remediation:</strong> {{.Remediation}}</p>
+</div>
+{{end}}{{end}}{{end}}
+</body>
+</html>
+`
+
+var (
+	markdownReportTmpl = template.Must(template.New("report").Parse(markdownReport))
+	htmlReportTmpl     = htmltemplate.Must(htmltemplate.New("report").Parse(htmlReport))
+)
+
+func renderReport(w io.Writer, name string, data reportData) error {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".md", ".markdown":
+		return markdownReportTmpl.Execute(w, data)
+	default:
+		return htmlReportTmpl.Execute(w, data)
+	}
+}
+
+func writeReport(name string, results []scanResult, now time.Time) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := renderReport(f, name, buildReport(results, now)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
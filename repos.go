@@ -0,0 +1,335 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// repoFile is a manifest found in a hosted repository: where to download it,
+// the credentials for that, and the browsable URL findings are reported on.
+// TS sources carry the path aliases of their tsconfig.json.
+type repoFile struct {
+	web     string
+	raw     string
+	headers map[string]string
+	repo    string
+	path    string
+	aliases *tsAliases
+}
+
+func apiGET(u string, headers map[string]string, v any) (http.Header, error) {
+	h := map[string]string{"Accept": "application/json"}
+	for k, val := range headers {
+		h[k] = val
+	}
+	body, status, header, err := httpGETHeader(u, h)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %d", u, status)
+	}
+	return header, json.Unmarshal(body, v)
+}
+
+func isRepoFile(p string) bool {
+	return !isVendoredPath(p) && (manifestRe.MatchString(p) || isTSSource(p) || isTSConfig(p))
+}
+
+// isVendoredPath reports paths inside installed third-party code.
+func isVendoredPath(p string) bool {
+	l := "/" + strings.ToLower(p) + "/"
+	return strings.Contains(l, "/node_modules/") || strings.Contains(l, "/vendor/") || strings.Contains(l, "/bower_components/") || strings.Contains(l, "/site-packages/")
+}
+
+// bitbucketDepth is how many levels one Bitbucket Cloud source listing goes
+// down; directories at the last level are listed again on their own.
+const bitbucketDepth = 6
+
+func gitlabFiles(base, group, token string) ([]repoFile, error) {
+	api := strings.TrimRight(base, "/") + "/api/v4"
+	auth := map[string]string{}
+	if token != "" {
+		auth["PRIVATE-TOKEN"] = token
+	}
+
+	type project struct {
+		ID            int    `json:"id"`
+		WebURL        string `json:"web_url"`
+		DefaultBranch string `json:"default_branch"`
+		Archived      bool   `json:"archived"`
+	}
+	var projects []project
+	for page := "1"; page != ""; {
+		var batch []project
+		u := fmt.Sprintf("%s/groups/%s/projects?include_subgroups=true&per_page=100&page=%s", api, url.PathEscape(group), page)
+		h, err := apiGET(u, auth, &batch)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, batch...)
+		page = h.Get("X-Next-Page")
+	}
+
+	var files []repoFile
+	for _, p := range projects {
+		if p.Archived || p.DefaultBranch == "" {
+			continue
+		}
+		for page := "1"; page != ""; {
+			var tree []struct {
+				Path string `json:"path"`
+				Type string `json:"type"`
+			}
+			u := fmt.Sprintf("%s/projects/%d/repository/tree?recursive=true&per_page=100&ref=%s&page=%s",
+				api, p.ID, url.QueryEscape(p.DefaultBranch), page)
+			h, err := apiGET(u, auth, &tree)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "gitlab: %v\n", err)
+				break
+			}
+			for _, t := range tree {
+				if t.Type != "blob" || !isRepoFile(t.Path) {
+					continue
+				}
+				files = append(files, repoFile{
+					web:     fmt.Sprintf("%s/-/blob/%s/%s", p.WebURL, p.DefaultBranch, t.Path),
+					raw:     fmt.Sprintf("%s/projects/%d/repository/files/%s/raw?ref=%s", api, p.ID, url.PathEscape(t.Path), url.QueryEscape(p.DefaultBranch)),
+					headers: auth,
+					repo:    p.WebURL,
+					path:    t.Path,
+				})
+			}
+			page = h.Get("X-Next-Page")
+		}
+	}
+	return files, nil
+}
+
+// bitbucketAuth accepts "user:app-password" for basic auth and anything else
+// as a bearer access token.
+func bitbucketAuth(token string) map[string]string {
+	if token == "" {
+		return nil
+	}
+	if strings.Contains(token, ":") {
+		return map[string]string{"Authorization": "Basic " + base64.StdEncoding.EncodeToString([]byte(token))}
+	}
+	return map[string]string{"Authorization": "Bearer " + token}
+}
+
+func bitbucketFiles(base, workspace, token string) ([]repoFile, error) {
+	base = strings.TrimRight(base, "/")
+	if p, err := url.Parse(base); err == nil && p.Host == "api.bitbucket.org" {
+		return bitbucketCloudFiles(base, workspace, bitbucketAuth(token))
+	}
+	return bitbucketServerFiles(base, workspace, bitbucketAuth(token))
+}
+
+func bitbucketCloudFiles(api, workspace string, auth map[string]string) ([]repoFile, error) {
+	type repo struct {
+		Slug       string `json:"slug"`
+		MainBranch struct {
+			Name string `json:"name"`
+		} `json:"mainbranch"`
+		Links struct {
+			HTML struct {
+				Href string `json:"href"`
+			} `json:"html"`
+		} `json:"links"`
+	}
+	var repos []repo
+	next := fmt.Sprintf("%s/2.0/repositories/%s?pagelen=100", api, url.PathEscape(workspace))
+	for next != "" {
+		var page struct {
+			Values []repo `json:"values"`
+			Next   string `json:"next"`
+		}
+		if _, err := apiGET(next, auth, &page); err != nil {
+			return nil, err
+		}
+		repos = append(repos, page.Values...)
+		next = page.Next
+	}
+
+	var files []repoFile
+	for _, r := range repos {
+		branch := r.MainBranch.Name
+		if branch == "" {
+			continue
+		}
+		src := fmt.Sprintf("%s/2.0/repositories/%s/%s/src/%s", api, url.PathEscape(workspace), r.Slug, url.PathEscape(branch))
+		dirs := []string{""}
+		for len(dirs) > 0 {
+			dir := dirs[0]
+			dirs = dirs[1:]
+			files = append(files, bitbucketCloudDir(src, dir, r.Links.HTML.Href, branch, auth, &dirs)...)
+		}
+	}
+	return files, nil
+}
+
+// bitbucketCloudDir lists the manifests up to bitbucketDepth levels below dir
+// and queues the directories the listing stopped at.
+func bitbucketCloudDir(src, dir, web, branch string, auth map[string]string, dirs *[]string) []repoFile {
+	var files []repoFile
+	depth := 0
+	if dir != "" {
+		depth = strings.Count(dir, "/") + 1
+	}
+	next := fmt.Sprintf("%s/%s?max_depth=%d&pagelen=100", src, strings.TrimPrefix(dir+"/", "/"), bitbucketDepth)
+	for next != "" {
+		var page struct {
+			Values []struct {
+				Path string `json:"path"`
+				Type string `json:"type"`
+			} `json:"values"`
+			Next string `json:"next"`
+		}
+		if _, err := apiGET(next, auth, &page); err != nil {
+			fmt.Fprintf(os.Stderr, "bitbucket: %v\n", err)
+			break
+		}
+		for _, v := range page.Values {
+			if v.Type == "commit_directory" && strings.Count(v.Path, "/")+1-depth == bitbucketDepth && !isVendoredPath(v.Path) {
+				*dirs = append(*dirs, v.Path)
+			}
+			if v.Type != "commit_file" || !isRepoFile(v.Path) {
+				continue
+			}
+			files = append(files, repoFile{
+				web:     fmt.Sprintf("%s/src/%s/%s", strings.TrimRight(web, "/"), branch, v.Path),
+				raw:     src + "/" + v.Path,
+				headers: auth,
+				repo:    src,
+				path:    v.Path,
+			})
+		}
+		next = page.Next
+	}
+	return files
+}
+
+func bitbucketServerFiles(base, project string, auth map[string]string) ([]repoFile, error) {
+	api := base + "/rest/api/1.0/projects/" + url.PathEscape(project)
+	var slugs []string
+	for start, last := 0, false; !last; {
+		var page struct {
+			Values []struct {
+				Slug string `json:"slug"`
+			} `json:"values"`
+			IsLastPage    bool `json:"isLastPage"`
+			NextPageStart int  `json:"nextPageStart"`
+		}
+		if _, err := apiGET(api+"/repos?limit=100&start="+strconv.Itoa(start), auth, &page); err != nil {
+			return nil, err
+		}
+		for _, v := range page.Values {
+			slugs = append(slugs, v.Slug)
+		}
+		start, last = page.NextPageStart, page.IsLastPage
+	}
+
+	var files []repoFile
+	for _, slug := range slugs {
+		repoAPI := api + "/repos/" + url.PathEscape(slug)
+		for start, last := 0, false; !last; {
+			var page struct {
+				Values        []string `json:"values"`
+				IsLastPage    bool     `json:"isLastPage"`
+				NextPageStart int      `json:"nextPageStart"`
+			}
+			if _, err := apiGET(repoAPI+"/files?limit=1000&start="+strconv.Itoa(start), auth, &page); err != nil {
+				fmt.Fprintf(os.Stderr, "bitbucket: %v\n", err)
+				break
+			}
+			for _, p := range page.Values {
+				if !isRepoFile(p) {
+					continue
+				}
+				files = append(files, repoFile{
+					web:     fmt.Sprintf("%s/projects/%s/repos/%s/browse/%s", base, url.PathEscape(project), url.PathEscape(slug), p),
+					raw:     repoAPI + "/raw/" + p,
+					headers: auth,
+					repo:    repoAPI,
+					path:    p,
+				})
+			}
+			start, last = page.NextPageStart, page.IsLastPage
+		}
+	}
+	return files, nil
+}
+
+func scanRepoFiles(files []repoFile, threads int) []scanResult {
+	scan := func(f repoFile) scanResult {
+		h := map[string]string{"User-Agent": randomUA()}
+		for k, v := range f.headers {
+			h[k] = v
+		}
+		body, status, err := httpGET(f.raw, h)
+		if err != nil {
+			return scanResult{u: f.web, err: categorize(errNetwork, err)}
+		}
+		switch status {
+		case http.StatusOK:
+		case http.StatusTooManyRequests:
+			return scanResult{u: f.web, err: categorize(errRateLimited, fmt.Errorf("raw file returned %d", status))}
+		case http.StatusNotFound, http.StatusGone:
+			return scanResult{u: f.web, err: categorize(errNotFound, fmt.Errorf("raw file returned %d", status))}
+		default:
+			return scanResult{u: f.web, err: categorize(errNetwork, fmt.Errorf("raw file returned %d", status))}
+		}
+		tc, err := parseContent(f.web, body)
+		if err != nil {
+			return scanResult{u: f.web, err: err}
+		}
+		if f.aliases != nil {
+			tc.deps = f.aliases.filter(tc.deps)
+		}
+		return scanResult{u: f.web, vulns: checkContent(tc, threads)}
+	}
+	worker := func(f repoFile) (scanResult, error) {
+		return safeScan(f.web, func() scanResult { return scan(f) }), nil
+	}
+	results, _ := runWorkers(files, worker, threads)
+	return results
+}
+
+func scanRepositories(threads int) []scanResult {
+	var files []repoFile
+	for _, g := range splitList(opts.gitlab) {
+		ff, err := gitlabFiles(opts.gitlabURL, g, opts.gitlabToken)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "gitlab %s: %v\n", g, err)
+		}
+		files = append(files, ff...)
+	}
+	for _, w := range splitList(opts.bitbucket) {
+		ff, err := bitbucketFiles(opts.bitbucketURL, w, opts.bitbucketToken)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "bitbucket %s: %v\n", w, err)
+		}
+		files = append(files, ff...)
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	return scanRepoFiles(attachTSConfigs(files), threads)
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
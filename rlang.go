@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+var (
+	cranURL         = "https://cran.r-project.org/web/packages/%s/index.html"
+	bioconductorURL = "https://bioconductor.org/packages/%s/"
+
+	// packages shipped with R itself
+	rBasePackages = map[string]struct{}{
+		"R": {}, "base": {}, "compiler": {}, "datasets": {}, "graphics": {}, "grDevices": {}, "grid": {},
+		"methods": {}, "parallel": {}, "splines": {}, "stats": {}, "stats4": {}, "tcltk": {}, "tools": {}, "utils": {},
+	}
+)
+
+func isRManifest(p string) bool {
+	switch path.Base(p) {
+	case "DESCRIPTION", "renv.lock":
+		return true
+	}
+	return false
+}
+
+func parseRManifest(name string, body []byte) ([]dependency, error) {
+	if path.Base(name) == "renv.lock" {
+		return parseRenvLock(body)
+	}
+	return parseDescription(body), nil
+}
+
+// dcfFields reads a Debian control style file, joining continuation lines.
+func dcfFields(body []byte) map[string]string {
+	fields := make(map[string]string)
+	var key string
+	sc := bufio.NewScanner(bytes.NewReader(body))
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			if key != "" {
+				fields[key] += " " + strings.TrimSpace(line)
+			}
+			continue
+		}
+		k, v, ok := strings.Cut(line, ":")
+		if !ok {
+			key = ""
+			continue
+		}
+		key = strings.TrimSpace(k)
+		fields[key] = strings.TrimSpace(v)
+	}
+	return fields
+}
+
+// parseDescription reads the package fields of an R DESCRIPTION file.
+// Packages installed from a GitHub remote are checked as owner/repo instead
+// of on CRAN; other remotes (gitlab::, url::, local::) are skipped.
+func parseDescription(body []byte) []dependency {
+	fields := dcfFields(body)
+	remote := make(map[string]struct{})
+	var deps []dependency
+	for _, r := range strings.Split(fields["Remotes"], ",") {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			continue
+		}
+		if kind, rest, ok := strings.Cut(r, "::"); ok {
+			if kind != "github" {
+				if i := strings.LastIndexAny(rest, "/:"); i >= 0 {
+					remote[strings.TrimSuffix(rest[i+1:], ".git")] = struct{}{}
+				}
+				continue
+			}
+			r = rest
+		}
+		if i := strings.IndexAny(r, "@#"); i >= 0 {
+			r = r[:i]
+		}
+		parts := strings.Split(r, "/")
+		if len(parts) < 2 {
+			continue
+		}
+		remote[parts[1]] = struct{}{}
+		deps = append(deps, dependency{Name: parts[0] + "/" + parts[1]})
+	}
+	for _, f := range []string{"Depends", "Imports", "LinkingTo", "Suggests", "Enhances"} {
+		// suggested and enhanced packages are only installed on request,
+		// usually for tests and vignettes
+		var typ depType
+		if f == "Suggests" || f == "Enhances" {
+			typ = depDev
+		}
+		for _, entry := range strings.Split(fields[f], ",") {
+			name, spec, _ := strings.Cut(strings.TrimSpace(entry), "(")
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if _, ok := rBasePackages[name]; ok {
+				continue
+			}
+			if _, ok := remote[name]; ok {
+				continue
+			}
+			deps = append(deps, dependency{Name: name, Spec: strings.TrimSpace(strings.TrimSuffix(spec, ")")), Type: typ})
+		}
+	}
+	return deps
+}
+
+// parseRenvLock reads renv.lock records. Packages from a repository other
+// than CRAN or Posit Package Manager carry its host as the private registry.
+func parseRenvLock(body []byte) ([]dependency, error) {
+	var lock struct {
+		R struct {
+			Repositories []struct {
+				Name string `json:"Name"`
+				URL  string `json:"URL"`
+			} `json:"Repositories"`
+		} `json:"R"`
+		Packages map[string]struct {
+			Package        string `json:"Package"`
+			Version        string `json:"Version"`
+			Source         string `json:"Source"`
+			Repository     string `json:"Repository"`
+			RemoteType     string `json:"RemoteType"`
+			RemoteUsername string `json:"RemoteUsername"`
+			RemoteRepo     string `json:"RemoteRepo"`
+		} `json:"Packages"`
+	}
+	if err := json.Unmarshal(body, &lock); err != nil {
+		return nil, err
+	}
+	repos := make(map[string]string)
+	for _, r := range lock.R.Repositories {
+		repos[r.Name] = privateRegistryHost(r.URL)
+	}
+	var deps []dependency
+	for key, p := range lock.Packages {
+		name := p.Package
+		if name == "" {
+			name = key
+		}
+		switch {
+		case strings.EqualFold(p.Source, "GitHub") || strings.EqualFold(p.RemoteType, "github"):
+			if p.RemoteUsername != "" && p.RemoteRepo != "" {
+				deps = append(deps, dependency{Name: p.RemoteUsername + "/" + p.RemoteRepo})
+			}
+		case strings.EqualFold(p.Source, "Repository"):
+			if _, ok := rBasePackages[name]; ok {
+				continue
+			}
+			deps = append(deps, dependency{Name: name, Spec: p.Version, PrivateRegistry: repos[p.Repository]})
+		}
+	}
+	return deps, nil
+}
+
+// isGitHubRemote reports whether an R dependency is an owner/repo remote
+// rather than a CRAN package name.
+func isGitHubRemote(pkg string, lang language) bool {
+	return lang == langR && strings.Contains(pkg, "/")
+}
+
+// bioconductorRegistered reports whether a package missing from CRAN is
+// published on Bioconductor, where install scripts usually fetch it from.
+func bioconductorRegistered(name string) bool {
+	r := packageLookups.do("bioconductor:"+name, func() (packageStatus, int) {
+		status, err := httpHEAD(strings.Replace(bioconductorURL, "%s", url.PathEscape(name), 1), map[string]string{"User-Agent": randomUA()})
+		if err != nil {
+			return packageStatus{}, 0
+		}
+		return packageStatus{unclaimed: status == http.StatusNotFound, status: status}, 0
+	})
+	if !shareable(r) {
+		packageLookups.forget("bioconductor:" + name)
+	}
+	return r.status == http.StatusOK
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDCFFields(t *testing.T) {
+	body := "Package: acmeutils\r\nTitle: Internal\r\n    Helpers\r\nImports:\r\n\tdplyr,\r\n\thttr\r\n\r\nnot a field\r\n  orphan continuation\r\n"
+	want := map[string]string{
+		"Package": "acmeutils",
+		"Title":   "Internal Helpers",
+		"Imports": " dplyr, httr",
+	}
+	if got := dcfFields([]byte(body)); !reflect.DeepEqual(got, want) {
+		t.Errorf("dcfFields = %q, want %q", got, want)
+	}
+}
+
+func TestParseDescription(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []dependency
+	}{
+		{
+			name: "fields, versions and base packages",
+			body: `Package: acmeutils
+Type: Package
+Version: 0.3.1
+Depends:
+    R (>= 4.1.0),
+    methods
+Imports:
+    dplyr (>= 1.1.0),
+    acmeauth,
+    httr
+LinkingTo: Rcpp
+Suggests:
+    testthat (>= 3.0.0),
+    knitr
+Enhances: acmeplots
+`,
+			want: []dependency{
+				{Name: "dplyr", Spec: ">= 1.1.0"},
+				{Name: "acmeauth"},
+				{Name: "httr"},
+				{Name: "Rcpp"},
+				{Name: "testthat", Spec: ">= 3.0.0", Type: depDev},
+				{Name: "knitr", Type: depDev},
+				{Name: "acmeplots", Type: depDev},
+			},
+		},
+		{
+			name: "remotes replace their CRAN lookup",
+			body: `Package: acmeapp
+Imports: acmeauth, acmedb, acmeplots, acmelegacy, shiny
+Remotes:
+    acme-r/acmeauth@v1.2,
+    github::acme-r/acmedb#42,
+    gitlab::acme/acmeplots,
+    url::https://r.acme.corp/src/acmelegacy.git
+`,
+			want: []dependency{
+				{Name: "acme-r/acmeauth"},
+				{Name: "acme-r/acmedb"},
+				{Name: "shiny"},
+			},
+		},
+		{
+			name: "empty",
+			body: "Package: nothing\nVersion: 1.0\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseDescription([]byte(tt.body)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRenvLock(t *testing.T) {
+	body := `{
+  "R": {
+    "Version": "4.3.2",
+    "Repositories": [
+      {"Name": "CRAN", "URL": "https://cloud.r-project.org"},
+      {"Name": "ACME", "URL": "https://cran.acme.corp/latest"}
+    ]
+  },
+  "Packages": {
+    "dplyr": {"Package": "dplyr", "Version": "1.1.4", "Source": "Repository", "Repository": "CRAN"},
+    "acmeauth": {"Package": "acmeauth", "Version": "0.2.0", "Source": "Repository", "Repository": "ACME"},
+    "acmedb": {"Package": "acmedb", "Version": "0.1.0", "Source": "GitHub", "RemoteType": "github", "RemoteUsername": "acme-r", "RemoteRepo": "acmedb"},
+    "acmeplots": {"Package": "acmeplots", "Version": "0.1.0", "Source": "git", "RemoteType": "github", "RemoteUsername": "acme-r", "RemoteRepo": "acmeplots"},
+    "broken": {"Package": "broken", "Source": "GitHub"},
+    "Biobase": {"Package": "Biobase", "Version": "2.62.0", "Source": "Bioconductor"},
+    "mypkg": {"Package": "mypkg", "Version": "0.0.1", "Source": "Local"},
+    "utils": {"Package": "utils", "Source": "Repository", "Repository": "CRAN"},
+    "keyonly": {"Version": "1.0", "Source": "Repository", "Repository": "CRAN"}
+  }
+}`
+	got, err := parseRenvLock([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []dependency{
+		{Name: "acme-r/acmedb"},
+		{Name: "acme-r/acmeplots"},
+		{Name: "acmeauth", Spec: "0.2.0", PrivateRegistry: "cran.acme.corp"},
+		{Name: "dplyr", Spec: "1.1.4"},
+		{Name: "keyonly", Spec: "1.0"},
+	}
+	if got = sortDeps(got); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+
+	if _, err := parseRenvLock([]byte("not json")); err == nil {
+		t.Error("invalid renv.lock parsed")
+	}
+}
+
+func TestParseRManifestDispatch(t *testing.T) {
+	if !isRManifest("pkg/DESCRIPTION") || !isRManifest("renv.lock") || isRManifest("DESCRIPTION.md") {
+		t.Error("isRManifest misclassified a file")
+	}
+	deps, err := parseRManifest("https://example.com/app/renv.lock", []byte(`{"Packages": {"shiny": {"Source": "Repository"}}}`))
+	if err != nil || !reflect.DeepEqual(deps, []dependency{{Name: "shiny"}}) {
+		t.Errorf("renv.lock dispatch = %+v, %v", deps, err)
+	}
+	deps, err = parseRManifest("https://example.com/app/DESCRIPTION", []byte("Imports: shiny\n"))
+	if err != nil || !reflect.DeepEqual(deps, []dependency{{Name: "shiny"}}) {
+		t.Errorf("DESCRIPTION dispatch = %+v, %v", deps, err)
+	}
+	if !isGitHubRemote("acme-r/acmedb", langR) || isGitHubRemote("acme-r/acmedb", langJS) || isGitHubRemote("dplyr", langR) {
+		t.Error("isGitHubRemote misclassified a name")
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"net/url"
+	"os"
+	"runtime/debug"
+	"sort"
+	"time"
+)
+
+var (
+	// secretFlags are left out of the run report; URLs keep their user but
+	// not their password.
+	secretFlags = map[string]bool{
+		"github-token": true, "gitlab-token": true, "bitbucket-token": true, "dojo-token": true, "fleet-token": true,
+	}
+	// endpoint URLs are credentials themselves (Slack and Teams hooks carry
+	// the secret in the path), so only their scheme and host are kept
+	endpointFlags = map[string]bool{"webhook": true, "export-url": true, "dojo-url": true}
+)
+
+type ecosystemStats struct {
+	Checked        int `json:"checked"`
+	Requests       int `json:"registry_requests"`
+	RegistryErrors int `json:"registry_errors"`
+	Findings       int `json:"findings"`
+}
+
+type runInputs struct {
+	Targets int `json:"targets"`
+	Scanned int `json:"scanned"`
+	Failed  int `json:"failed"`
+}
+
+type urlError struct {
+	URL   string         `json:"url"`
+	Error *scanErrorJSON `json:"error"`
+}
+
+// runReport is the -run-report artifact: everything a run did, for tools
+// that would otherwise scrape stdout.
+type runReport struct {
+	Version         string                    `json:"version"`
+	Commit          string                    `json:"commit,omitempty"`
+	GoVersion       string                    `json:"go_version"`
+	StartedAt       time.Time                 `json:"started_at"`
+	FinishedAt      time.Time                 `json:"finished_at"`
+	DurationSeconds float64                   `json:"duration_seconds"`
+	Flags           map[string]string         `json:"flags"`
+	Inputs          runInputs                 `json:"inputs"`
+	Ecosystems      map[string]ecosystemStats `json:"ecosystems"`
+	ErrorCategories map[errorCategory]int     `json:"error_categories"`
+	Findings        []groupedFinding          `json:"findings"`
+	Errors          []urlError                `json:"errors"`
+}
+
+// runInfo is what a run report needs from before the scan: the registry
+// counters are cumulative, so daemon rounds report the difference.
+type runInfo struct {
+	started  time.Time
+	targets  int
+	counters map[string]ecosystemStats
+}
+
+func startRun(targets int) runInfo {
+	return runInfo{started: time.Now(), targets: targets, counters: ecosystemCounters()}
+}
+
+func ecosystemCounters() map[string]ecosystemStats {
+	stats := make(map[string]ecosystemStats)
+	for reg, n := range metricPackages.values() {
+		s := stats[reg]
+		s.Checked = int(n)
+		stats[reg] = s
+	}
+	for reg, n := range metricRegistryReqs.values() {
+		s := stats[reg]
+		s.Requests = int(n)
+		stats[reg] = s
+	}
+	for reg, n := range metricRegistryErrors.values() {
+		s := stats[reg]
+		s.RegistryErrors = int(n)
+		stats[reg] = s
+	}
+	return stats
+}
+
+func toolVersion() (version, commit, goVersion string) {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown", "", ""
+	}
+	for _, s := range bi.Settings {
+		if s.Key == "vcs.revision" {
+			commit = s.Value
+		}
+	}
+	return bi.Main.Version, commit, bi.GoVersion
+}
+
+// setFlags returns the flags given on the command line or by -profile.
+func setFlags() map[string]string {
+	flags := make(map[string]string)
+	flag.Visit(func(f *flag.Flag) {
+		if secretFlags[f.Name] {
+			flags[f.Name] = "REDACTED"
+			return
+		}
+		v := f.Value.String()
+		u, err := url.Parse(v)
+		switch {
+		case err != nil:
+		case endpointFlags[f.Name]:
+			v = (&url.URL{Scheme: u.Scheme, Host: u.Host}).String()
+		case u.User != nil:
+			v = u.Redacted()
+		}
+		flags[f.Name] = v
+	})
+	return flags
+}
+
+func buildRunReport(results []scanResult, run runInfo, now time.Time) runReport {
+	r := runReport{
+		StartedAt:       run.started.UTC(),
+		FinishedAt:      now.UTC(),
+		DurationSeconds: now.Sub(run.started).Seconds(),
+		Flags:           setFlags(),
+		Inputs:          runInputs{Targets: run.targets, Scanned: len(results)},
+		Ecosystems:      make(map[string]ecosystemStats),
+		ErrorCategories: make(map[errorCategory]int),
+		Findings:        groupFindings(results),
+		Errors:          []urlError{},
+	}
+	r.Version, r.Commit, r.GoVersion = toolVersion()
+	for reg, s := range ecosystemCounters() {
+		before := run.counters[reg]
+		r.Ecosystems[reg] = ecosystemStats{
+			Checked:        s.Checked - before.Checked,
+			Requests:       s.Requests - before.Requests,
+			RegistryErrors: s.RegistryErrors - before.RegistryErrors,
+		}
+	}
+	for _, res := range results {
+		for _, v := range res.vulns {
+			s := r.Ecosystems[registryFor(v.Language)]
+			s.Findings++
+			r.Ecosystems[registryFor(v.Language)] = s
+		}
+		if res.err != nil {
+			r.Inputs.Failed++
+			r.ErrorCategories[errorCategoryOf(res.err)]++
+			r.Errors = append(r.Errors, urlError{URL: res.u, Error: errorJSON(res.err)})
+		}
+	}
+	for reg, s := range r.Ecosystems {
+		if s == (ecosystemStats{}) {
+			delete(r.Ecosystems, reg)
+		}
+	}
+	if r.Findings == nil {
+		r.Findings = []groupedFinding{}
+	}
+	sort.Slice(r.Errors, func(i, j int) bool { return r.Errors[i].URL < r.Errors[j].URL })
+	return r
+}
+
+// writeRunReport writes the report through a temporary file, so a daemon
+// round never leaves a half-written one for whatever is watching it.
+func writeRunReport(name string, results []scanResult, run runInfo, now time.Time) error {
+	b, err := json.MarshalIndent(buildRunReport(results, run, now), "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := name + ".tmp"
+	if err := os.WriteFile(tmp, append(b, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var nodeModulesRe = regexp.MustCompile(`node_modules/((?:@[\w.-]+/)?[\w.-]+)`)
+
+type sourceMap struct {
+	SourceRoot     string    `json:"sourceRoot"`
+	Sources        []string  `json:"sources"`
+	SourcesContent []*string `json:"sourcesContent"`
+}
+
+func isSourceMap(p string) bool {
+	return strings.HasSuffix(strings.ToLower(p), ".map")
+}
+
+// parseSourceMap scans the inline sourcesContent of a sourcemap and names the
+// node_modules paths it bundles. Sources without inline content are returned
+// as links to fetch: same-origin only unless -allow-cross-origin-maps.
+func parseSourceMap(mapURL string, body []byte) (targetContent, error) {
+	var m sourceMap
+	if err := json.Unmarshal(body, &m); err != nil {
+		return targetContent{}, err
+	}
+	base, err := url.Parse(mapURL)
+	if err != nil {
+		return targetContent{}, err
+	}
+
+	set := make(map[string]struct{})
+	var links []string
+	for i, src := range m.Sources {
+		for _, sub := range nodeModulesRe.FindAllStringSubmatch(src, -1) {
+			set[sub[1]] = struct{}{}
+		}
+		if i < len(m.SourcesContent) && m.SourcesContent[i] != nil {
+			if strings.Contains(src, "node_modules/") {
+				continue
+			}
+			for _, p := range extractPackagesFromJS(*m.SourcesContent[i]) {
+				set[p] = struct{}{}
+			}
+			continue
+		}
+		if u := sourceURL(base, m.SourceRoot, src); u != "" {
+			links = append(links, u)
+		}
+	}
+
+	names := make([]string, 0, len(set))
+	for n := range set {
+		names = append(names, n)
+	}
+	return targetContent{deps: namesToDeps(names), lang: langJS, conf: confMedium, body: body, links: links}, nil
+}
+
+func sourceURL(base *url.URL, root, src string) string {
+	if root != "" && !strings.Contains(src, "://") && !strings.HasPrefix(src, "/") {
+		src = strings.TrimRight(root, "/") + "/" + src
+	}
+	ref, err := url.Parse(src)
+	if err != nil {
+		return ""
+	}
+	u := base.ResolveReference(ref)
+	if u.Scheme != "http" && u.Scheme != "https" || strings.Contains(u.Path, "/node_modules/") {
+		return ""
+	}
+	if !opts.allowCrossOriginMaps && !strings.EqualFold(u.Host, base.Host) {
+		return ""
+	}
+	u.Path = path.Clean(u.Path)
+	return u.String()
+}
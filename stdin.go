@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// scanStdinManifest checks manifest content piped in on stdin. name is the
+// file name it is parsed as; without -type JSON is read as package.json (or
+// a lockfile) and anything else as requirements.txt.
+func scanStdinManifest(name string) ([]scanResult, error) {
+	body, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return nil, err
+	}
+	// UTF-16 from Windows editors and shells would not sniff as JSON
+	body = decodeText(body)
+	if name == "" {
+		name = sniffManifestType(body)
+	}
+	tc, err := parseContent(name, body)
+	if err != nil {
+		return nil, fmt.Errorf("parsing stdin as %s: %w", name, err)
+	}
+	results := []scanResult{{u: "stdin:" + name, vulns: checkContent(tc, opts.threads)}}
+	rateFindings(results)
+	return results, nil
+}
+
+func sniffManifestType(body []byte) string {
+	var probe map[string]json.RawMessage
+	if json.Unmarshal(decodeText(body), &probe) != nil {
+		return "requirements.txt"
+	}
+	switch {
+	case probe["lockfileVersion"] != nil:
+		return "package-lock.json"
+	case probe["_meta"] != nil:
+		return "Pipfile.lock"
+	case probe["Packages"] != nil:
+		return "renv.lock"
+	case probe["manifest_version"] != nil:
+		return "manifest.json"
+	}
+	return "package.json"
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/binary"
+	"testing"
+	"unicode/utf16"
+)
+
+// encodeUTF16 renders s the way Windows tools write text, with an optional
+// byte order mark.
+func encodeUTF16(s string, order binary.AppendByteOrder, bom bool) []byte {
+	var b []byte
+	units := utf16.Encode([]rune(s))
+	if bom {
+		units = append([]uint16{0xFEFF}, units...)
+	}
+	for _, u := range units {
+		b = order.AppendUint16(b, u)
+	}
+	return b
+}
+
+func TestSniffManifestType(t *testing.T) {
+	const pkg = `{"name": "web", "dependencies": {"acme-ui": "^1.0.0"}}`
+	tests := []struct {
+		name string
+		body []byte
+		want string
+	}{
+		{"package.json", []byte(pkg), "package.json"},
+		{"package-lock.json", []byte(`{"name": "web", "lockfileVersion": 3, "packages": {}}`), "package-lock.json"},
+		{"Pipfile.lock", []byte(`{"_meta": {"sources": []}, "default": {}}`), "Pipfile.lock"},
+		{"renv.lock", []byte(`{"R": {"Version": "4.3.2"}, "Packages": {}}`), "renv.lock"},
+		{"extension manifest", []byte(`{"manifest_version": 3, "name": "Acme"}`), "manifest.json"},
+		{"requirements.txt", []byte("requests==2.31.0\nacme-internal>=1.0\n"), "requirements.txt"},
+		{"JSON array", []byte(`["acme-ui"]`), "requirements.txt"},
+		{"empty", nil, "requirements.txt"},
+		{"UTF-8 BOM", append([]byte{0xEF, 0xBB, 0xBF}, pkg...), "package.json"},
+		{"UTF-16LE with BOM", encodeUTF16(`{"lockfileVersion": 2}`, binary.LittleEndian, true), "package-lock.json"},
+		{"UTF-16BE with BOM", encodeUTF16(pkg, binary.BigEndian, true), "package.json"},
+		{"UTF-16LE without BOM", encodeUTF16(`{"_meta": {}}`, binary.LittleEndian, false), "Pipfile.lock"},
+		{"UTF-16 requirements", encodeUTF16("requests==2.31.0\r\n", binary.LittleEndian, true), "requirements.txt"},
+	}
+	for _, tt := range tests {
+		if got := sniffManifestType(tt.body); got != tt.want {
+			t.Errorf("%s: sniffManifestType = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseSniffedUTF16Manifest(t *testing.T) {
+	body := encodeUTF16("{\r\n  \"dependencies\": {\"acme-ui\": \"^1.0.0\"}\r\n}\r\n", binary.LittleEndian, true)
+	name := sniffManifestType(body)
+	tc, err := parseContent(name, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tc.lang != langJS || len(tc.deps) != 1 || tc.deps[0].Name != "acme-ui" || tc.deps[0].Spec != "^1.0.0" {
+		t.Errorf("parseContent(%q) = %s %+v", name, tc.lang, tc.deps)
+	}
+}
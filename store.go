@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// The results store is driven through the sqlite3 command line shell so the
+// tool keeps building with the standard library only.
+const storeSchema = `
+CREATE TABLE IF NOT EXISTS findings (
+	package    TEXT NOT NULL,
+	language   TEXT NOT NULL,
+	url        TEXT NOT NULL,
+	status     INTEGER NOT NULL,
+	state      TEXT NOT NULL,
+	first_seen TEXT NOT NULL,
+	last_seen  TEXT NOT NULL,
+	claimed_at TEXT,
+	PRIMARY KEY (package, language, url)
+);
+CREATE TABLE IF NOT EXISTS transitions (
+	package    TEXT NOT NULL,
+	language   TEXT NOT NULL,
+	url        TEXT NOT NULL,
+	from_state TEXT NOT NULL,
+	to_state   TEXT NOT NULL,
+	at         TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS findings_state ON findings (state);
+CREATE TABLE IF NOT EXISTS confirmations (
+	package        TEXT NOT NULL,
+	language       TEXT NOT NULL,
+	url            TEXT NOT NULL,
+	token          TEXT NOT NULL,
+	protocol       TEXT NOT NULL,
+	remote_address TEXT,
+	at             TEXT NOT NULL
+);
+`
+
+func sqlQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// checkSQLite fails early when the sqlite3 command -db writes through is
+// missing, instead of after the scan.
+func checkSQLite() error {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		return fmt.Errorf("-db needs the sqlite3 command line tool in PATH: %w", err)
+	}
+	return nil
+}
+
+func runSQLite(db, script string) error {
+	_, err := querySQLite(db, script)
+	return err
+}
+
+// querySQLite runs script and returns the rows it selects, one per line with
+// tab-separated columns.
+func querySQLite(db, script string) ([]string, error) {
+	cmd := exec.Command("sqlite3", "-bail", "-separator", "\t", db)
+	cmd.Stdin = strings.NewReader(script)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("sqlite3: %s", msg)
+		}
+		return nil, fmt.Errorf("sqlite3: %w", err)
+	}
+	var rows []string
+	for _, l := range strings.Split(stdout.String(), "\n") {
+		if l != "" {
+			rows = append(rows, l)
+		}
+	}
+	return rows, nil
+}
+
+// recordFindings stores the unclaimed names of a scan. A name that a rescan
+// no longer reports is only marked claimed once the registry says so: it may
+// just as well have been dropped from the manifest or filtered out, which is
+// recorded as gone.
+func recordFindings(db string, results []scanResult, now time.Time) error {
+	ts := sqlQuote(now.UTC().Format(time.RFC3339))
+
+	var seen strings.Builder
+	seen.WriteString("CREATE TEMP TABLE scanned (url TEXT PRIMARY KEY);\n")
+	seen.WriteString("CREATE TEMP TABLE seen (package TEXT, language TEXT, url TEXT, status INTEGER);\n")
+	for _, r := range results {
+		if r.err != nil {
+			continue
+		}
+		fmt.Fprintf(&seen, "INSERT OR IGNORE INTO scanned VALUES (%s);\n", sqlQuote(r.u))
+		for _, v := range r.vulns {
+			if v.Kind != kindUnclaimed {
+				continue
+			}
+			fmt.Fprintf(&seen, "INSERT INTO seen VALUES (%s, %s, %s, %d);\n",
+				sqlQuote(v.Package), sqlQuote(string(v.Language)), sqlQuote(r.u), v.Status)
+		}
+	}
+
+	// unclaimed names missing from a successful rescan of their URL
+	seen.WriteString(`CREATE TEMP TABLE missing AS
+SELECT f.package, f.language, f.url FROM findings f
+WHERE f.state = 'unclaimed'
+	AND f.url IN (SELECT url FROM scanned)
+	AND NOT EXISTS (SELECT 1 FROM seen s WHERE s.package = f.package AND s.language = f.language AND s.url = f.url);
+`)
+	rows, err := querySQLite(db, storeSchema+seen.String()+"SELECT DISTINCT package, language FROM missing;\n")
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	b.WriteString(storeSchema)
+	b.WriteString("BEGIN;\n")
+	b.WriteString(seen.String())
+	b.WriteString("CREATE TEMP TABLE rechecked (package TEXT, language TEXT, state TEXT);\n")
+	for _, row := range rows {
+		pkg, lang, _ := strings.Cut(row, "\t")
+		var state string
+		switch _, status := isUnclaimed(pkg, language(lang)); status {
+		case http.StatusOK, http.StatusFound:
+			state = "claimed"
+		case http.StatusNotFound, http.StatusGone:
+			state = "gone"
+		default:
+			// no conclusive answer, ask again on the next scan
+			continue
+		}
+		fmt.Fprintf(&b, "INSERT INTO rechecked VALUES (%s, %s, '%s');\n", sqlQuote(pkg), sqlQuote(lang), state)
+	}
+
+	// names that were claimed or gone and are reported again
+	fmt.Fprintf(&b, `INSERT INTO transitions
+SELECT f.package, f.language, f.url, f.state, 'unclaimed', %[1]s
+FROM findings f JOIN seen s USING (package, language, url)
+WHERE f.state != 'unclaimed';
+INSERT INTO findings (package, language, url, status, state, first_seen, last_seen)
+SELECT package, language, url, status, 'unclaimed', %[1]s, %[1]s FROM seen WHERE true
+ON CONFLICT (package, language, url) DO UPDATE SET
+	status = excluded.status, state = 'unclaimed', last_seen = excluded.last_seen, claimed_at = NULL;
+`, ts)
+
+	// missing names take the state the registry now reports for them
+	fmt.Fprintf(&b, `INSERT INTO transitions
+SELECT m.package, m.language, m.url, 'unclaimed', r.state, %[1]s
+FROM missing m JOIN rechecked r USING (package, language);
+UPDATE findings SET state = r.state, claimed_at = CASE r.state WHEN 'claimed' THEN %[1]s END
+FROM missing m JOIN rechecked r USING (package, language)
+WHERE findings.package = m.package AND findings.language = m.language AND findings.url = m.url;
+`, ts)
+	b.WriteString("COMMIT;\n")
+
+	return runSQLite(db, b.String())
+}
+
+func recordConfirmations(db string, cc []confirmation) error {
+	var b strings.Builder
+	b.WriteString(storeSchema)
+	b.WriteString("BEGIN;\n")
+	for _, c := range cc {
+		// one row per URL the finding was reported on, so confirmations
+		// join the findings on (package, language, url)
+		urls := c.URLs
+		if len(urls) == 0 {
+			urls = []string{""}
+		}
+		for _, u := range urls {
+			fmt.Fprintf(&b, "INSERT INTO confirmations VALUES (%s, %s, %s, %s, %s, %s, %s);\n",
+				sqlQuote(c.Package), sqlQuote(string(c.Language)), sqlQuote(u), sqlQuote(c.Token), sqlQuote(c.Protocol),
+				sqlQuote(c.RemoteAddress), sqlQuote(c.At.UTC().Format(time.RFC3339)))
+		}
+	}
+	b.WriteString("COMMIT;\n")
+	return runSQLite(db, b.String())
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"unicode/utf16"
+)
+
+const (
+	// regexes run over windows of this size so minified bundles, often a
+	// single multi-megabyte line, can't make a lazy match span the whole file
+	scanChunk = 256 * 1024
+	// a match shorter than the overlap is whole in at least one window
+	scanOverlap = 4 * 1024
+)
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// decodeText returns body as UTF-8 without a byte order mark. Windows-hosted
+// servers send manifests as UTF-16, with or without a BOM.
+func decodeText(body []byte) []byte {
+	switch {
+	case bytes.HasPrefix(body, utf8BOM):
+		return body[len(utf8BOM):]
+	case bytes.HasPrefix(body, []byte{0xFF, 0xFE}):
+		return fromUTF16(body[2:], binary.LittleEndian)
+	case bytes.HasPrefix(body, []byte{0xFE, 0xFF}):
+		return fromUTF16(body[2:], binary.BigEndian)
+	}
+	if order := guessUTF16(body); order != nil {
+		return fromUTF16(body, order)
+	}
+	return body
+}
+
+// guessUTF16 recognizes BOM-less UTF-16 by the NUL that ASCII text has in
+// every other byte.
+func guessUTF16(body []byte) binary.ByteOrder {
+	n := min(len(body), 512) &^ 1
+	if n < 4 {
+		return nil
+	}
+	var even, odd int
+	for i := 0; i < n; i += 2 {
+		if body[i] == 0 {
+			even++
+		}
+		if body[i+1] == 0 {
+			odd++
+		}
+	}
+	pairs := n / 2
+	switch {
+	case odd*10 >= pairs*9 && even == 0:
+		return binary.LittleEndian
+	case even*10 >= pairs*9 && odd == 0:
+		return binary.BigEndian
+	}
+	return nil
+}
+
+func fromUTF16(b []byte, order binary.ByteOrder) []byte {
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = order.Uint16(b[2*i:])
+	}
+	return []byte(string(utf16.Decode(units)))
+}
+
+// scanChunks splits s into overlapping windows of scanChunk bytes; s itself
+// when it is shorter.
+func scanChunks(s string) []string {
+	if len(s) <= scanChunk {
+		return []string{s}
+	}
+	var out []string
+	for start := 0; ; start += scanChunk - scanOverlap {
+		end := min(start+scanChunk, len(s))
+		out = append(out, s[start:end])
+		if end == len(s) {
+			return out
+		}
+	}
+}
+
+// lineScanner reads body line by line. Lines may be as long as body itself,
+// where bufio's default would stop at the first minified one.
+func lineScanner(body []byte) *bufio.Scanner {
+	sc := bufio.NewScanner(bytes.NewReader(body))
+	sc.Buffer(make([]byte, 0, min(len(body)+1, 64*1024)), len(body)+1)
+	return sc
+}
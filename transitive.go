@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type transitiveDep struct {
+	name, spec string
+	path       []string
+	// typ is the type of the direct dependency the path starts at
+	typ depType
+}
+
+// npmDependencies returns what a published npm package pulls in: the pinned
+// version when spec names one, otherwise the latest release.
+func npmDependencies(pkg, spec string) map[string]string {
+	m, err := fetchNPMMeta(pkg)
+	if err != nil {
+		return nil
+	}
+	raw, ok := m.Versions[strings.TrimPrefix(strings.TrimSpace(spec), "=")]
+	if !ok {
+		raw, ok = m.Versions[m.DistTags["latest"]]
+	}
+	if !ok {
+		return nil
+	}
+	var v struct {
+		Dependencies         map[string]string `json:"dependencies"`
+		OptionalDependencies map[string]string `json:"optionalDependencies"`
+		PeerDependencies     map[string]string `json:"peerDependencies"`
+	}
+	if json.Unmarshal(raw, &v) != nil {
+		return nil
+	}
+	out := make(map[string]string)
+	for _, set := range []map[string]string{v.Dependencies, v.OptionalDependencies, v.PeerDependencies} {
+		for name, spec := range set {
+			// npm: aliases install the aliased package
+			if real, ok := strings.CutPrefix(spec, "npm:"); ok {
+				name, spec = splitNameSpec(real)
+			}
+			if isRegistrySpec(spec) {
+				out[name] = spec
+			}
+		}
+	}
+	return out
+}
+
+func isRegistrySpec(spec string) bool {
+	l := strings.ToLower(strings.TrimSpace(spec))
+	for _, p := range nonVersionPfx {
+		if strings.HasPrefix(l, p) {
+			return false
+		}
+	}
+	return !strings.Contains(l, "/")
+}
+
+// checkTransitive walks the npm dependencies of claimed direct dependencies
+// up to opts.depth levels and reports names nobody has registered. A claimed
+// package with a dangling dependency installs whatever gets published there.
+func checkTransitive(level []transitiveDep, seen map[string]struct{}, conf confidence, threads int) []vuln {
+	type outp struct {
+		v    *vuln
+		next []transitiveDep
+	}
+	var vulns []vuln
+	for depth := 0; depth < opts.depth && len(level) > 0; depth++ {
+		expand := func(d transitiveDep) (outp, error) {
+			var o outp
+			for name, spec := range npmDependencies(d.name, d.spec) {
+				o.next = append(o.next, transitiveDep{name: name, spec: spec, path: append(append([]string(nil), d.path...), name), typ: d.typ})
+			}
+			return o, nil
+		}
+		outs, _ := runWorkers(level, expand, threads)
+
+		var candidates []transitiveDep
+		for _, o := range outs {
+			for _, d := range o.next {
+				if _, ok := seen[d.name]; ok {
+					continue
+				}
+				seen[d.name] = struct{}{}
+				candidates = append(candidates, d)
+			}
+		}
+		check := func(d transitiveDep) (outp, error) {
+			isV, code := isUnclaimed(d.name, langJS)
+			if isV {
+				return outp{v: &vuln{Package: d.name, Status: code, Language: langJS, Kind: kindTransitive,
+					Detail: strings.Join(d.path, " > "), Confidence: conf, Type: d.typ}}, nil
+			}
+			if code == http.StatusOK {
+				return outp{next: []transitiveDep{d}}, nil
+			}
+			return outp{}, nil
+		}
+		outs, _ = runWorkers(candidates, check, threads)
+
+		level = level[:0]
+		for _, o := range outs {
+			if o.v != nil {
+				vulns = append(vulns, *o.v)
+			}
+			level = append(level, o.next...)
+		}
+	}
+	return vulns
+}
+
+func transitiveSummary(v vuln) string {
+	parent := v.Detail
+	if i := strings.LastIndex(parent, " > "); i >= 0 {
+		parent = parent[:i]
+	}
+	return fmt.Sprintf("The npm package %s is not registered, but it is a dependency of published packages the target installs (%s). "+
+		"Anyone can publish it and have it installed through %s, which the target never names itself.", v.Package, v.Detail, parent)
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// tsAliases are the module specifiers a tsconfig.json (or jsconfig.json)
+// resolves locally through compilerOptions.paths and baseUrl. They look like
+// npm packages ("@app/utils") but never reach a registry.
+type tsAliases struct {
+	exact    map[string]struct{}
+	prefixes []string
+	// top-level files and directories under baseUrl, importable bare
+	local map[string]struct{}
+}
+
+type tsConfig struct {
+	Extends         any `json:"extends"`
+	CompilerOptions struct {
+		BaseURL *string             `json:"baseUrl"`
+		Paths   map[string][]string `json:"paths"`
+	} `json:"compilerOptions"`
+}
+
+func isTSConfig(p string) bool {
+	b := strings.ToLower(path.Base(p))
+	return (strings.HasPrefix(b, "tsconfig") || strings.HasPrefix(b, "jsconfig")) && strings.HasSuffix(b, ".json")
+}
+
+func isTSSource(p string) bool {
+	l := strings.ToLower(p)
+	if strings.HasSuffix(l, ".d.ts") {
+		return false
+	}
+	switch path.Ext(l) {
+	case ".ts", ".tsx", ".mts", ".cts":
+		return true
+	}
+	return false
+}
+
+// stripJSONC removes the comments and trailing commas tsconfig files allow.
+func stripJSONC(b []byte) []byte {
+	out := make([]byte, 0, len(b))
+	inStr := false
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		switch {
+		case inStr:
+			out = append(out, c)
+			if c == '\\' && i+1 < len(b) {
+				i++
+				out = append(out, b[i])
+			} else if c == '"' {
+				inStr = false
+			}
+		case c == '"':
+			inStr = true
+			out = append(out, c)
+		case c == '/' && i+1 < len(b) && b[i+1] == '/':
+			for i < len(b) && b[i] != '\n' {
+				i++
+			}
+			if i < len(b) {
+				out = append(out, '\n')
+			}
+		case c == '/' && i+1 < len(b) && b[i+1] == '*':
+			i += 2
+			for i+1 < len(b) && !(b[i] == '*' && b[i+1] == '/') {
+				i++
+			}
+			i++
+		case c == '}' || c == ']':
+			j := len(out) - 1
+			for j >= 0 && (out[j] == ' ' || out[j] == '\t' || out[j] == '\n' || out[j] == '\r') {
+				j--
+			}
+			if j >= 0 && out[j] == ',' {
+				out = append(out[:j], out[j+1:]...)
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func parseTSConfig(body []byte) (tsConfig, error) {
+	var c tsConfig
+	// editors on Windows save tsconfig.json with a BOM, which tsc accepts
+	err := json.Unmarshal(stripJSONC(decodeText(body)), &c)
+	return c, err
+}
+
+// extendsPaths returns the repository paths of the relative configs c extends;
+// package configs ("@tsconfig/node18") carry no project aliases.
+func (c tsConfig) extendsPaths(dir string) []string {
+	var refs []string
+	switch e := c.Extends.(type) {
+	case string:
+		refs = []string{e}
+	case []any:
+		for _, v := range e {
+			if s, ok := v.(string); ok {
+				refs = append(refs, s)
+			}
+		}
+	}
+	var out []string
+	for _, r := range refs {
+		if !strings.HasPrefix(r, ".") {
+			continue
+		}
+		if !strings.HasSuffix(strings.ToLower(r), ".json") {
+			r += ".json"
+		}
+		out = append(out, path.Join(dir, r))
+	}
+	return out
+}
+
+// resolveTSAliases merges the config at p with the configs it extends, the
+// nearest definition of paths and baseUrl winning as in tsc. sources are the
+// repository's TS files, used to list what baseUrl makes importable.
+func resolveTSAliases(p string, configs map[string]tsConfig, sources []string) *tsAliases {
+	var paths map[string][]string
+	var baseURL, baseDir string
+	haveBase := false
+	seen := make(map[string]struct{})
+	for queue := []string{p}; len(queue) > 0; {
+		cur := queue[0]
+		queue = queue[1:]
+		if _, ok := seen[cur]; ok {
+			continue
+		}
+		seen[cur] = struct{}{}
+		c, ok := configs[cur]
+		if !ok {
+			continue
+		}
+		if paths == nil && c.CompilerOptions.Paths != nil {
+			paths = c.CompilerOptions.Paths
+		}
+		if !haveBase && c.CompilerOptions.BaseURL != nil {
+			haveBase = true
+			baseURL, baseDir = *c.CompilerOptions.BaseURL, path.Dir(cur)
+		}
+		queue = append(queue, c.extendsPaths(path.Dir(cur))...)
+	}
+
+	a := &tsAliases{exact: make(map[string]struct{}), local: make(map[string]struct{})}
+	for k := range paths {
+		switch {
+		case k == "*":
+			// catch-all fallbacks also cover real packages
+		case strings.HasSuffix(k, "*"):
+			a.prefixes = append(a.prefixes, strings.TrimSuffix(k, "*"))
+		default:
+			a.exact[k] = struct{}{}
+		}
+	}
+	if haveBase {
+		root := path.Join(baseDir, baseURL)
+		for _, s := range sources {
+			rel := strings.TrimPrefix(s, root+"/")
+			if root == "." {
+				rel = s
+			} else if rel == s {
+				continue
+			}
+			first, _, _ := strings.Cut(rel, "/")
+			a.local[strings.TrimSuffix(first, path.Ext(first))] = struct{}{}
+		}
+	}
+	return a
+}
+
+func (a *tsAliases) matches(spec string) bool {
+	if _, ok := a.exact[spec]; ok {
+		return true
+	}
+	for _, p := range a.prefixes {
+		if strings.HasPrefix(spec, p) {
+			return true
+		}
+	}
+	first, _, _ := strings.Cut(spec, "/")
+	if strings.HasPrefix(first, "@") {
+		return false
+	}
+	_, ok := a.local[first]
+	return ok
+}
+
+func (a *tsAliases) filter(deps []dependency) []dependency {
+	out := deps[:0]
+	for _, d := range deps {
+		if !a.matches(d.Name) {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// attachTSConfigs downloads the tsconfig files found in each repository,
+// gives every TS source the aliases of its nearest config and drops the
+// configs from the scan list.
+func attachTSConfigs(files []repoFile) []repoFile {
+	type repoState struct {
+		configs map[string]tsConfig
+		sources []string
+	}
+	repos := make(map[string]*repoState)
+	var out []repoFile
+	for _, f := range files {
+		r := repos[f.repo]
+		if r == nil {
+			r = &repoState{configs: make(map[string]tsConfig)}
+			repos[f.repo] = r
+		}
+		if !isTSConfig(f.path) {
+			if isTSSource(f.path) {
+				r.sources = append(r.sources, f.path)
+			}
+			out = append(out, f)
+			continue
+		}
+		h := map[string]string{"User-Agent": randomUA()}
+		for k, v := range f.headers {
+			h[k] = v
+		}
+		body, status, err := httpGET(f.raw, h)
+		if err != nil || status != http.StatusOK {
+			continue
+		}
+		c, err := parseTSConfig(body)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "tsconfig %s: %v\n", f.web, err)
+			continue
+		}
+		r.configs[f.path] = c
+	}
+
+	cache := make(map[string]*tsAliases)
+	for i, f := range out {
+		r := repos[f.repo]
+		if !isTSSource(f.path) || len(r.configs) == 0 {
+			continue
+		}
+		for dir := path.Dir(f.path); ; dir = path.Dir(dir) {
+			var found string
+			for _, name := range []string{"tsconfig.json", "jsconfig.json"} {
+				if _, ok := r.configs[path.Join(dir, name)]; ok {
+					found = path.Join(dir, name)
+					break
+				}
+			}
+			if found != "" {
+				key := f.repo + "\x00" + found
+				if cache[key] == nil {
+					cache[key] = resolveTSAliases(found, r.configs, r.sources)
+				}
+				out[i].aliases = cache[key]
+				break
+			}
+			if dir == "." || dir == "/" {
+				break
+			}
+		}
+	}
+	return out
+}
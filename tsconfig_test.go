@@ -0,0 +1,116 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestStripJSONC(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"plain", `{"a": 1}`, `{"a": 1}`},
+		{"line comment", "{\n  // comment\n  \"a\": 1\n}", "{\n  \n  \"a\": 1\n}"},
+		{"block comment", `{/* x */"a": /* y */1}`, `{"a": 1}`},
+		{"trailing commas", "{\"a\": [1, 2,],\n}", "{\"a\": [1, 2]\n}"},
+		{"comma before a comment", "{\"a\": 1, // last\n}", "{\"a\": 1 \n}"},
+		{"slashes in strings", `{"paths": {"@app/*": ["src/app/*"]}, "u": "https://x//y"}`, `{"paths": {"@app/*": ["src/app/*"]}, "u": "https://x//y"}`},
+		{"escaped quote in string", `{"a": "say \"//hi\""}`, `{"a": "say \"//hi\""}`},
+		{"unterminated block comment", `{"a": 1} /* open`, `{"a": 1} `},
+		{"comment at end of file", `{"a": 1} // end`, `{"a": 1} `},
+	}
+	for _, tt := range tests {
+		if got := string(stripJSONC([]byte(tt.in))); got != tt.want {
+			t.Errorf("%s: stripJSONC(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseTSConfig(t *testing.T) {
+	body := "\ufeff" + `{
+  // generated by create-app
+  "extends": ["@tsconfig/node18/tsconfig.json", "./tsconfig.base"],
+  "compilerOptions": {
+    "baseUrl": "./src", /* imports resolve from here */
+    "paths": {
+      "@app/*": ["app/*"],
+      "~config": ["config/index.ts"],
+    },
+  },
+}`
+	c, err := parseTSConfig([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.CompilerOptions.BaseURL == nil || *c.CompilerOptions.BaseURL != "./src" {
+		t.Errorf("baseUrl = %v, want ./src", c.CompilerOptions.BaseURL)
+	}
+	want := map[string][]string{"@app/*": {"app/*"}, "~config": {"config/index.ts"}}
+	if !reflect.DeepEqual(c.CompilerOptions.Paths, want) {
+		t.Errorf("paths = %v, want %v", c.CompilerOptions.Paths, want)
+	}
+	if got := c.extendsPaths("web"); !reflect.DeepEqual(got, []string{"web/tsconfig.base.json"}) {
+		t.Errorf("extendsPaths = %q", got)
+	}
+}
+
+func TestResolveTSAliases(t *testing.T) {
+	cfg := func(body string) tsConfig {
+		c, err := parseTSConfig([]byte(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	configs := map[string]tsConfig{
+		"web/tsconfig.json":      cfg(`{"extends": "../tsconfig.base.json", "compilerOptions": {"strict": true}}`),
+		"tsconfig.base.json":     cfg(`{"extends": "./tsconfig.paths", "compilerOptions": {"baseUrl": "web/src"}}`),
+		"tsconfig.paths.json":    cfg(`{"compilerOptions": {"paths": {"@app/*": ["web/src/app/*"], "shared": ["libs/shared/index.ts"], "*": ["types/*"]}}}`),
+		"loop/tsconfig.json":     cfg(`{"extends": "./tsconfig.json"}`),
+		"nobase/tsconfig.json":   cfg(`{"compilerOptions": {"paths": {"#lib/*": ["lib/*"]}}}`),
+		"rootbase/tsconfig.json": cfg(`{"compilerOptions": {"baseUrl": "."}}`),
+	}
+	sources := []string{"web/src/components/Button.tsx", "web/src/utils.ts", "web/src/app/main.ts", "api/server.ts", "rootbase/lib/x.ts"}
+
+	a := resolveTSAliases("web/tsconfig.json", configs, sources)
+	tests := []struct {
+		spec string
+		want bool
+	}{
+		{"@app/store", true},
+		{"shared", true},
+		{"shared/extra", false},
+		{"components/Button", true},
+		{"utils", true},
+		{"app", true},
+		{"react", false},
+		{"@acme/ui", false},
+		{"api", false},
+	}
+	for _, tt := range tests {
+		if got := a.matches(tt.spec); got != tt.want {
+			t.Errorf("matches(%q) = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+
+	deps := []dependency{{Name: "react"}, {Name: "@app/store"}, {Name: "utils"}, {Name: "@acme/ui"}}
+	var names []string
+	for _, d := range a.filter(deps) {
+		names = append(names, d.Name)
+	}
+	sort.Strings(names)
+	if !reflect.DeepEqual(names, []string{"@acme/ui", "react"}) {
+		t.Errorf("filter kept %q", names)
+	}
+
+	if a := resolveTSAliases("loop/tsconfig.json", configs, sources); a.matches("react") {
+		t.Error("a config extending itself produced aliases")
+	}
+	if a := resolveTSAliases("nobase/tsconfig.json", configs, sources); !a.matches("#lib/x") || a.matches("utils") {
+		t.Error("paths without baseUrl resolved wrongly")
+	}
+	if a := resolveTSAliases("rootbase/tsconfig.json", configs, sources); !a.matches("lib") || a.matches("web") {
+		t.Error("baseUrl . did not list the config's own directory")
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"math/rand"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// toolUA identifies DCHero honestly, for -registry-ua tool.
+const toolUA = "dchero (+https://github.com/luq0x/DCHero)"
+
+var (
+	jitterLanguages = []string{"en-US,en;q=0.9", "en-GB,en;q=0.9", "en-US,en;q=0.8,de;q=0.6", "en;q=0.9", "en-US,en;q=0.9,fr;q=0.7"}
+	jitterAccepts   = []string{"*/*", "application/json, text/plain, */*", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"}
+)
+
+// loadUserAgents replaces the built-in User-Agent rotation with the lines of
+// file.
+func loadUserAgents(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var uas []string
+	for _, l := range readLines(f) {
+		if !strings.HasPrefix(l, "#") {
+			uas = append(uas, l)
+		}
+	}
+	if len(uas) > 0 {
+		userAgents = uas
+	}
+	return nil
+}
+
+// targetHeaders returns the headers of a request to a target: -ua when
+// given, the rotation otherwise, and -header-jitter noise on top. Registries
+// and APIs always get the plain rotation.
+func targetHeaders() map[string]string {
+	h := map[string]string{"User-Agent": opts.ua}
+	if opts.ua == "" {
+		h["User-Agent"] = randomUA()
+	}
+	if !opts.headerJitter {
+		return h
+	}
+	h["Accept-Language"] = jitterLanguages[rand.Intn(len(jitterLanguages))]
+	h["Accept"] = jitterAccepts[rand.Intn(len(jitterAccepts))]
+	if rand.Intn(2) == 0 {
+		h["DNT"] = "1"
+	}
+	if rand.Intn(3) == 0 {
+		h["Cache-Control"] = "no-cache"
+	}
+	return h
+}
+
+// applyUA sets -registry-ua on an outgoing registry request.
+func applyUA(req *http.Request) {
+	if !isRegistryHost(strings.ToLower(req.URL.Host)) {
+		return
+	}
+	switch opts.registryUA {
+	case "":
+	case "tool":
+		req.Header.Set("User-Agent", toolUA)
+	default:
+		req.Header.Set("User-Agent", opts.registryUA)
+	}
+}
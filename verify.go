@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+type verdict string
+
+const (
+	verdictExploitable verdict = "exploitable"
+	verdictClaimed     verdict = "claimed"
+	verdictUnknown     verdict = "unknown"
+)
+
+// verifiedFinding is a finding with the answer of a fresh registry check.
+type verifiedFinding struct {
+	groupedFinding
+	Verdict        verdict    `json:"verdict"`
+	VerifiedStatus int        `json:"verified_status"`
+	ClaimedAt      *time.Time `json:"claimed_at,omitempty"`
+	CheckedAt      time.Time  `json:"checked_at"`
+}
+
+// readFindings loads findings from -json output, an -export payload or an
+// earlier verify -json run.
+func readFindings(name string) ([]groupedFinding, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	type entry struct {
+		vuln
+		URL      string   `json:"url"`
+		URLs     []string `json:"urls"`
+		Findings []vuln   `json:"findings"`
+	}
+	var results []scanResult
+	add := func(raw json.RawMessage) error {
+		var e entry
+		if err := json.Unmarshal(raw, &e); err != nil {
+			return err
+		}
+		if len(e.Findings) > 0 {
+			results = append(results, scanResult{u: e.URL, vulns: e.Findings})
+			return nil
+		}
+		if e.Package == "" {
+			return nil
+		}
+		urls := e.URLs
+		if len(urls) == 0 {
+			urls = []string{e.URL}
+		}
+		for _, u := range urls {
+			results = append(results, scanResult{u: u, vulns: []vuln{e.vuln}})
+		}
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		items := []json.RawMessage{raw}
+		if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("[")) {
+			if err := json.Unmarshal(raw, &items); err != nil {
+				return nil, err
+			}
+		}
+		for _, it := range items {
+			if err := add(it); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return groupFindings(results), nil
+}
+
+// verifiable reports whether a finding claims a package name is free, which
+// is what verify re-checks; the other kinds are about packages that exist.
+func verifiable(v vuln) bool {
+	switch v.Kind {
+	case "", kindUnclaimed, kindTransitive:
+		return v.Language != langBrowserExt
+	}
+	return false
+}
+
+// verifyOnce asks the registry about v without going through the caches and
+// returns the status and, for claimed names, when they were first published.
+func verifyOnce(v vuln) (int, *time.Time) {
+	h := map[string]string{"User-Agent": randomUA(), "Accept": "application/json"}
+	switch v.Language {
+	case langJS:
+		body, status, err := httpGET(registryURL(v.Package, langJS), h)
+		if err != nil {
+			return 0, nil
+		}
+		var m npmPackument
+		if json.Unmarshal(body, &m) != nil || status != http.StatusOK {
+			return status, nil
+		}
+		if _, removed := m.unpublished(); removed {
+			return http.StatusNotFound, nil
+		}
+		return status, npmCreated(&m)
+	case langPython:
+		body, status, err := httpGET(fmt.Sprintf(pypiJSONURL, v.Package), h)
+		if err != nil {
+			return 0, nil
+		}
+		var m pypiProject
+		if json.Unmarshal(body, &m) != nil || status != http.StatusOK {
+			return status, nil
+		}
+		return status, pypiFirstUpload(&m)
+	case langHelm:
+		packageLookups.forget("artifacthub:" + v.Package)
+		_, status := onArtifactHub(v.Package)
+		return status, nil
+	case langVSCode:
+		extensionLookups.forget(v.Package)
+		kind, status, _ := checkExtension(v.Package)
+		if kind == "" && status == http.StatusNotFound {
+			// the name is free but every publisher it could go to is taken
+			return http.StatusOK, nil
+		}
+		return status, nil
+	}
+	_, status, err := httpGET(registryURL(v.Package, v.Language), h)
+	if err != nil {
+		return 0, nil
+	}
+	if v.Language == langR && status == http.StatusNotFound {
+		packageLookups.forget("bioconductor:" + v.Package)
+		if bioconductorRegistered(v.Package) {
+			return http.StatusOK, nil
+		}
+	}
+	return status, nil
+}
+
+func npmCreated(m *npmPackument) *time.Time {
+	var t time.Time
+	if raw, ok := m.Time["created"]; ok && json.Unmarshal(raw, &t) == nil {
+		return &t
+	}
+	return nil
+}
+
+func pypiFirstUpload(m *pypiProject) *time.Time {
+	var first time.Time
+	for _, files := range m.Releases {
+		for _, f := range files {
+			if first.IsZero() || (!f.UploadTime.IsZero() && f.UploadTime.Before(first)) {
+				first = f.UploadTime
+			}
+		}
+	}
+	if first.IsZero() {
+		return nil
+	}
+	return &first
+}
+
+// verifyFinding only calls a name exploitable when every attempt says it is
+// free, so a flaky CDN or a stale cache node can't make the report.
+func verifyFinding(g groupedFinding, attempts int) verifiedFinding {
+	out := verifiedFinding{groupedFinding: g, Verdict: verdictUnknown}
+	free := 0
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			time.Sleep(time.Duration(i) * time.Second)
+		}
+		status, claimedAt := verifyOnce(g.vuln)
+		switch status {
+		case http.StatusOK, http.StatusFound:
+			out.Verdict, out.VerifiedStatus, out.ClaimedAt = verdictClaimed, status, claimedAt
+			out.CheckedAt = time.Now().UTC()
+			return out
+		case http.StatusNotFound, http.StatusGone:
+			free++
+			if free == attempts {
+				out.Verdict, out.VerifiedStatus = verdictExploitable, status
+			}
+		default:
+			// keep the answer that stood in the way
+			out.VerifiedStatus = status
+		}
+	}
+	out.CheckedAt = time.Now().UTC()
+	return out
+}
+
+func printVerified(f verifiedFinding) {
+	tag := fmt.Sprintf("[%s|%s]", f.Package, f.Registry)
+	var note string
+	switch f.Verdict {
+	case verdictExploitable:
+		tag = red + tag + reset
+		note = "still unclaimed"
+	case verdictClaimed:
+		note = "claimed"
+		if f.ClaimedAt != nil {
+			note += fmt.Sprintf(" %s (%d days ago)", f.ClaimedAt.UTC().Format("2006-01-02"), int(time.Since(*f.ClaimedAt).Hours()/24))
+		}
+	case verdictUnknown:
+		note = fmt.Sprintf("unknown (registry answered %d)", f.VerifiedStatus)
+		if f.VerifiedStatus == 0 {
+			note = "unknown (registry unreachable)"
+		}
+	}
+	fmt.Printf("%s %s %s\n", tag, note, strings.Join(f.URLs, " "))
+}
+
+// runVerify re-checks earlier findings against the registries, for reports
+// written days after the scan.
+func runVerify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	threads := fs.Int("t", 10, "concurrent registry checks")
+	attempts := fs.Int("attempts", 3, "registry checks per package; all must agree before a name counts as free")
+	asJSON := fs.Bool("json", false, "print one JSON object per finding")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("usage: dchero verify [flags] findings.json")
+	}
+	if *attempts < 1 {
+		*attempts = 1
+	}
+
+	groups, err := readFindings(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	var todo []groupedFinding
+	for _, g := range groups {
+		if verifiable(g.vuln) {
+			todo = append(todo, g)
+		}
+	}
+	if skipped := len(groups) - len(todo); skipped > 0 {
+		fmt.Fprintf(os.Stderr, "skipping %d findings that are not unclaimed names\n", skipped)
+	}
+
+	verified, _ := runWorkers(todo, func(g groupedFinding) (verifiedFinding, error) {
+		return verifyFinding(g, *attempts), nil
+	}, *threads)
+	counts := make(map[verdict]int)
+	enc := json.NewEncoder(os.Stdout)
+	for _, f := range verified {
+		counts[f.Verdict]++
+		if *asJSON {
+			enc.Encode(f)
+			continue
+		}
+		printVerified(f)
+	}
+	fmt.Fprintf(os.Stderr, "%d exploitable, %d claimed, %d unknown\n", counts[verdictExploitable], counts[verdictClaimed], counts[verdictUnknown])
+	return nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	versionNumRe  = regexp.MustCompile(`^v?(\d+(?:\.\d+)*)`)
+	comparatorRe  = regexp.MustCompile(`(>=|<=|===|==|~=|!=|>|<|\^|~|=)?\s*v?(\d+(?:\.(?:\d+|[xX*]))*)`)
+	prereleaseRe  = regexp.MustCompile(`(?i)(-|\d\.?(a|b|rc|dev|alpha|beta|pre)\d*)`)
+	nonVersionPfx = []string{"file:", "link:", "workspace:", "git", "http:", "https:", "github:", "npm:", "portal:", "patch:"}
+)
+
+// parseVersion keeps the numeric release segments, which is enough to order
+// both semver and the common PEP 440 forms.
+func parseVersion(v string) []int {
+	m := versionNumRe.FindStringSubmatch(strings.TrimSpace(v))
+	if m == nil {
+		return nil
+	}
+	var out []int
+	for _, p := range strings.Split(m[1], ".") {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			break
+		}
+		out = append(out, n)
+	}
+	return out
+}
+
+func compareVersions(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func maxVersion(versions []string) (string, []int) {
+	var best string
+	var bestV []int
+	var pre string
+	var preV []int
+	for _, s := range versions {
+		v := parseVersion(s)
+		if v == nil {
+			continue
+		}
+		if prereleaseRe.MatchString(s) {
+			if preV == nil || compareVersions(v, preV) > 0 {
+				pre, preV = s, v
+			}
+			continue
+		}
+		if bestV == nil || compareVersions(v, bestV) > 0 {
+			best, bestV = s, v
+		}
+	}
+	if bestV == nil {
+		return pre, preV
+	}
+	return best, bestV
+}
+
+// specAboveMax reports whether no version up to max can satisfy spec, judged
+// by the lower bound of every alternative in the range.
+func specAboveMax(spec string, max []int) bool {
+	spec = strings.TrimSpace(spec)
+	l := strings.ToLower(spec)
+	if l == "" || l == "*" || l == "latest" || l == "x" || strings.Contains(l, "/") {
+		return false
+	}
+	for _, p := range nonVersionPfx {
+		if strings.HasPrefix(l, p) {
+			return false
+		}
+	}
+	for _, alt := range strings.Split(spec, "||") {
+		bounded := false
+		for _, m := range comparatorRe.FindAllStringSubmatch(alt, -1) {
+			op := m[1]
+			if op == "<" || op == "<=" || op == "!=" {
+				continue
+			}
+			lower := parseVersion(m[2])
+			if lower == nil {
+				continue
+			}
+			c := compareVersions(lower, max)
+			if c < 0 || (c == 0 && op != ">") {
+				return false
+			}
+			bounded = true
+			break
+		}
+		if !bounded {
+			return false
+		}
+	}
+	return true
+}
+
+func publicVersions(pkg string, lang language) []string {
+	var out []string
+	switch lang {
+	case langJS:
+		m, err := fetchNPMMeta(pkg)
+		if err != nil {
+			return nil
+		}
+		for v := range m.Versions {
+			out = append(out, v)
+		}
+	case langPython:
+		m, err := fetchPyPIMeta(pkg)
+		if err != nil {
+			return nil
+		}
+		for v, files := range m.Releases {
+			for _, f := range files {
+				if !f.Yanked {
+					out = append(out, v)
+					break
+				}
+			}
+		}
+	}
+	return out
+}
+
+// versionGap flags a claimed public name whose releases all sit below what
+// the manifest asks for: the build must be getting it from somewhere else.
+func versionGap(pkg, spec string, lang language) (bool, string) {
+	maxStr, max := maxVersion(publicVersions(pkg, lang))
+	if max == nil || !specAboveMax(spec, max) {
+		return false, ""
+	}
+	return true, fmt.Sprintf("requests %s, highest public version %s", spec, maxStr)
+}
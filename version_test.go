@@ -0,0 +1,108 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []int
+	}{
+		{"1.2.3", []int{1, 2, 3}},
+		{"v2.0", []int{2, 0}},
+		{" 10.4.0-beta.1 ", []int{10, 4, 0}},
+		{"2.0.0rc1", []int{2, 0, 0}},
+		{"2024.1", []int{2024, 1}},
+		{"1.x", []int{1}},
+		{"latest", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := parseVersion(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseVersion(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b []int
+		want int
+	}{
+		{[]int{1, 2, 3}, []int{1, 2, 3}, 0},
+		{[]int{1, 2}, []int{1, 2, 0}, 0},
+		{[]int{1, 10}, []int{1, 9}, 1},
+		{[]int{1, 2, 3}, []int{2}, -1},
+		{nil, []int{0}, 0},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%v, %v) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestMaxVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		versions []string
+		want     string
+	}{
+		{"numeric order", []string{"1.9.0", "1.10.0", "1.2.0"}, "1.10.0"},
+		{"prereleases lose to releases", []string{"1.0.0", "2.0.0-beta.1", "2.0.0rc1"}, "1.0.0"},
+		{"only prereleases", []string{"0.1.0-alpha", "0.2.0-alpha"}, "0.2.0-alpha"},
+		{"pep 440 dev releases", []string{"3.1", "3.2.dev1"}, "3.1"},
+		{"unparsable skipped", []string{"nightly", "0.0.1"}, "0.0.1"},
+		{"nothing", nil, ""},
+	}
+	for _, tt := range tests {
+		if got, _ := maxVersion(tt.versions); got != tt.want {
+			t.Errorf("%s: maxVersion(%q) = %q, want %q", tt.name, tt.versions, got, tt.want)
+		}
+	}
+}
+
+func TestSpecAboveMax(t *testing.T) {
+	max := []int{1, 4, 2}
+	tests := []struct {
+		spec string
+		want bool
+	}{
+		// npm ranges
+		{"^2.0.0", true},
+		{"~1.5.0", true},
+		{"^1.0.0", false},
+		{"1.4.2", false},
+		{">1.4.2", true},
+		{">=1.4.2", false},
+		{">=1.4.3 <2", true},
+		{"2.x", true},
+		{"1.x", false},
+		{"^2.0.0 || ^1.0.0", false},
+		{"^2.0.0 || ^3.0.0", true},
+		{"<3.0.0", false},
+		{"*", false},
+		{"latest", false},
+		{"", false},
+		{"workspace:^2.0.0", false},
+		{"npm:other@^9.0.0", false},
+		{"file:../local", false},
+		{"git+https://git.acme.corp/x.git#v9.0.0", false},
+		{"acme/tool#v9", false},
+		// PEP 440 specifiers
+		{">=2.0", true},
+		{"==9.9.9", true},
+		{"~=1.5", true},
+		{"!=1.0,>=2.1", true},
+		{">=1.0,<3", false},
+		{"==1.4.2", false},
+		{"===1.4.2", false},
+	}
+	for _, tt := range tests {
+		if got := specAboveMax(tt.spec, max); got != tt.want {
+			t.Errorf("specAboveMax(%q, 1.4.2) = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}